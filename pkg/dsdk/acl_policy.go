@@ -39,6 +39,11 @@ func (e *AclPolicy) Get(ro *AclPolicyGetRequest) (*AclPolicy, *ApiErrorResponse,
 	return resp, nil, nil
 }
 
+func (e *AclPolicy) GetE(ro *AclPolicyGetRequest) (*AclPolicy, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AclPolicySetRequest struct {
 	Ctxt            context.Context    `json:"-"`
 	Initiators      []*Initiator       `json:"initiators,omitempty" mapstructure:"initiators"`
@@ -62,6 +67,11 @@ func (e *AclPolicy) Set(ro *AclPolicySetRequest) (*AclPolicy, *ApiErrorResponse,
 
 }
 
+func (e *AclPolicy) SetE(ro *AclPolicySetRequest) (*AclPolicy, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AclPolicyReloadRequest struct {
 	Ctxt context.Context `json:"-"`
 }
@@ -81,3 +91,8 @@ func (e *AclPolicy) Reload(ro *AclPolicyReloadRequest) (*AclPolicy, *ApiErrorRes
 	}
 	return resp, nil, nil
 }
+
+func (e *AclPolicy) ReloadE(ro *AclPolicyReloadRequest) (*AclPolicy, error) {
+	resp, apierr, err := e.Reload(ro)
+	return resp, AsError(apierr, err)
+}
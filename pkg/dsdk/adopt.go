@@ -0,0 +1,179 @@
+package dsdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+
+	greq "github.com/levigross/grequests"
+)
+
+// CreateOutcome tells whether a create call made a new object or adopted one
+// that already matched the request
+type CreateOutcome int
+
+const (
+	// Created means the cluster created the object in response to the call
+	Created CreateOutcome = iota
+	// Adopted means an earlier attempt of the call had already created the
+	// object, eg. before a connection error or a 503, and it was returned
+	// instead of creating it again
+	Adopted
+)
+
+func (o CreateOutcome) String() string {
+	switch o {
+	case Created:
+		return "created"
+	case Adopted:
+		return "adopted"
+	}
+	return fmt.Sprintf("CreateOutcome(%d)", int(o))
+}
+
+// adoptCheck describes the object a create call makes so retries can find it
+type adoptCheck struct {
+	// path is where the object is found once created
+	path    string
+	request map[string]interface{}
+	outcome CreateOutcome
+	// sent is set once an attempt failed in a way that doesn't rule out the
+	// cluster having created the object, an object found before that already
+	// existed when the call was made
+	sent bool
+}
+
+// attempted records the outcome of an attempt of the create call
+func (check *adoptCheck) attempted(resp *greq.Response, rerr, err error) {
+	if rerr != nil {
+		// a refused connection never reached the cluster
+		check.sent = check.sent || err != ErrConnection
+		return
+	}
+	check.sent = check.sent || resp.StatusCode >= 500
+}
+
+func withAdoptCheck(ctxt context.Context, check *adoptCheck) context.Context {
+	return context.WithValue(ctxt, adoptCheckCtxKey, check)
+}
+
+func adoptCheckFrom(ctxt context.Context) *adoptCheck {
+	check, _ := ctxt.Value(adoptCheckCtxKey).(*adoptCheck)
+	return check
+}
+
+// CreateOrAdopt POSTs ro to url to create the object found at url/id.  Once
+// an attempt failed with a connection error or a 5xx, the object is looked up
+// before the request is sent again, or when a retry gets a 409, and returned
+// as Adopted if it exists and matches the request.  An object that exists but
+// doesn't match results in an error matching ErrConflict, as does a 409 to
+// the first attempt since the object was there before the call
+func (c *ApiConnection) CreateOrAdopt(ctxt context.Context, url, id string, ro *greq.RequestOptions) (*ApiOuter, CreateOutcome, *ApiErrorResponse, error) {
+	if id == "" || ro == nil {
+		rs, apierr, err := c.Post(ctxt, url, ro)
+		return rs, Created, apierr, err
+	}
+	check := &adoptCheck{path: path.Join(url, id)}
+	if jdata, err := json.Marshal(ro.JSON); err == nil {
+		json.Unmarshal(jdata, &check.request)
+	}
+	rs, apierr, err := c.Post(withAdoptCheck(ctxt, check), url, ro)
+	return rs, check.outcome, apierr, err
+}
+
+// adopt looks for the object a failed create call may have made anyway.  It
+// reports whether rs was filled with it, or an error if an object that
+// doesn't match the request is in the way
+func (c *ApiConnection) adopt(ctxt context.Context, method string, ro *greq.RequestOptions, rs interface{}) (bool, error) {
+	check := adoptCheckFrom(ctxt)
+	if check == nil || method != "POST" || !check.sent {
+		return false, nil
+	}
+	outer, ok := rs.(*ApiOuter)
+	if !ok {
+		return false, nil
+	}
+	headers := make(map[string]string, len(ro.Headers))
+	for k, v := range ro.Headers {
+		headers[k] = v
+	}
+	existing := &ApiOuter{}
+	apierr, err := c.do(ctxt, "GET", check.path, &greq.RequestOptions{Headers: headers}, existing, !canRetry, !isSensitive, !allowLogin)
+	if apierr != nil || err != nil {
+		// most likely it doesn't exist, but if the cluster is still unhealthy
+		// the retry will tell
+		return false, nil
+	}
+	for k, v := range check.request {
+		// only compare what the cluster reports back, and leave nested
+		// objects like storage_instances alone
+		ev, ok := existing.Data[k]
+		if !ok || isNested(v) {
+			continue
+		}
+		if !reflect.DeepEqual(v, ev) {
+			return false, fmt.Errorf("%s exists with %s %v instead of %v: %w", check.path, k, ev, v, ErrConflict)
+		}
+	}
+	c.log(ctxt).Infof("Adopting %s created by an earlier attempt", check.path)
+	*outer = *existing
+	check.outcome = Adopted
+	return true, nil
+}
+
+func isNested(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+// CreateOrAdopt is Create, additionally reporting whether the AppInstance was
+// created or adopted after a retry, see ApiConnection.CreateOrAdopt
+func (e *AppInstances) CreateOrAdopt(ro *AppInstancesCreateRequest) (*AppInstance, CreateOutcome, *ApiErrorResponse, error) {
+	gro := &greq.RequestOptions{JSON: ro}
+	rs, outcome, apierr, err := GetConn(ro.Ctxt).CreateOrAdopt(ro.Ctxt, e.Path, ro.Name, gro)
+	if apierr != nil {
+		return nil, outcome, apierr, err
+	}
+	if err != nil {
+		return nil, outcome, nil, err
+	}
+	resp := &AppInstance{}
+	if err = FillStruct(rs.Data, resp); err != nil {
+		return nil, outcome, nil, err
+	}
+	RegisterAppInstanceEndpoints(resp)
+	return resp, outcome, nil, nil
+}
+
+func (e *AppInstances) CreateOrAdoptE(ro *AppInstancesCreateRequest) (*AppInstance, CreateOutcome, error) {
+	resp, outcome, apierr, err := e.CreateOrAdopt(ro)
+	return resp, outcome, AsError(apierr, err)
+}
+
+// CreateOrAdopt is Create, additionally reporting whether the Initiator was
+// created or adopted after a retry, see ApiConnection.CreateOrAdopt
+func (e *Initiators) CreateOrAdopt(ro *InitiatorsCreateRequest) (*Initiator, CreateOutcome, *ApiErrorResponse, error) {
+	gro := &greq.RequestOptions{JSON: ro}
+	rs, outcome, apierr, err := GetConn(ro.Ctxt).CreateOrAdopt(ro.Ctxt, e.Path, ro.Id, gro)
+	if apierr != nil {
+		return nil, outcome, apierr, err
+	}
+	if err != nil {
+		return nil, outcome, nil, err
+	}
+	resp := &Initiator{}
+	if err = FillStruct(rs.Data, resp); err != nil {
+		return nil, outcome, nil, err
+	}
+	return resp, outcome, nil, nil
+}
+
+func (e *Initiators) CreateOrAdoptE(ro *InitiatorsCreateRequest) (*Initiator, CreateOutcome, error) {
+	resp, outcome, apierr, err := e.CreateOrAdopt(ro)
+	return resp, outcome, AsError(apierr, err)
+}
@@ -75,8 +75,8 @@ func newAppInstances(path string) *AppInstances {
 
 func (e *AppInstances) Create(ro *AppInstancesCreateRequest) (*AppInstance, *ApiErrorResponse, error) {
 	gro := &greq.RequestOptions{JSON: ro}
-	rs, apierr, err := GetConn(ro.Ctxt).Post(ro.Ctxt, e.Path, gro)
-	Log().Debugf("App Instance create request sent to go-sdk with following data, %#v", ro)
+	rs, _, apierr, err := GetConn(ro.Ctxt).CreateOrAdopt(ro.Ctxt, e.Path, ro.Name, gro)
+	GetConn(ro.Ctxt).log(ro.Ctxt).Debugf("App Instance create request sent to go-sdk with following data, %#v", ro)
 	if apierr != nil {
 		return nil, apierr, err
 	}
@@ -91,6 +91,11 @@ func (e *AppInstances) Create(ro *AppInstancesCreateRequest) (*AppInstance, *Api
 	return resp, nil, nil
 }
 
+func (e *AppInstances) CreateE(ro *AppInstancesCreateRequest) (*AppInstance, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AppInstancesListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -120,6 +125,11 @@ func (e *AppInstances) List(ro *AppInstancesListRequest) ([]*AppInstance, *ApiEr
 	return resp, nil, nil
 }
 
+func (e *AppInstances) ListE(ro *AppInstancesListRequest) ([]*AppInstance, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AppInstancesGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Id   string          `json:"-"`
@@ -142,6 +152,11 @@ func (e *AppInstances) Get(ro *AppInstancesGetRequest) (*AppInstance, *ApiErrorR
 	return resp, nil, nil
 }
 
+func (e *AppInstances) GetE(ro *AppInstancesGetRequest) (*AppInstance, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AppInstanceSetRequest struct {
 	Ctxt               context.Context    `json:"-"`
 	AdminState         string             `json:"admin_state,omitempty" mapstructure:"admin_state"`
@@ -175,6 +190,11 @@ func (e *AppInstance) Set(ro *AppInstanceSetRequest) (*AppInstance, *ApiErrorRes
 	return resp, nil, nil
 }
 
+func (e *AppInstance) SetE(ro *AppInstanceSetRequest) (*AppInstance, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AppInstanceDeleteRequest struct {
 	Ctxt  context.Context `json:"-"`
 	Force bool            `json:"force,omitempty" mapstructure:"force"`
@@ -196,6 +216,11 @@ func (e *AppInstance) Delete(ro *AppInstanceDeleteRequest) (*AppInstance, *ApiEr
 	return resp, nil, nil
 }
 
+func (e *AppInstance) DeleteE(ro *AppInstanceDeleteRequest) (*AppInstance, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AppInstanceAppTemplate struct {
 	Path           string `json:"path,omitempty" mapstructure:"path"`
 	ResolvedPath   string `json:"resolved_path,omitempty" mapstructure:"resolved_path"`
@@ -220,6 +245,11 @@ func (e *AppInstance) GetMetadata(ro *AppInstanceMetadataGetRequest) (*AppInstan
 	return stringifyResults(rs), nil, nil
 }
 
+func (e *AppInstance) GetMetadataE(ro *AppInstanceMetadataGetRequest) (*AppInstanceMetadata, error) {
+	resp, apierr, err := e.GetMetadata(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AppInstanceMetadataSetRequest struct {
 	Ctxt     context.Context `json:"-"`
 	Metadata map[string]string
@@ -237,6 +267,11 @@ func (e *AppInstance) SetMetadata(ro *AppInstanceMetadataSetRequest) (*AppInstan
 	return stringifyResults(rs), nil, nil
 }
 
+func (e *AppInstance) SetMetadataE(ro *AppInstanceMetadataSetRequest) (*AppInstanceMetadata, error) {
+	resp, apierr, err := e.SetMetadata(ro)
+	return resp, AsError(apierr, err)
+}
+
 func stringifyResults(rs *ApiOuter) *AppInstanceMetadata {
 	resp := &AppInstanceMetadata{}
 	for k, v := range rs.Data {
@@ -277,3 +312,8 @@ func (e *AppInstance) Reload(ro *AppInstanceReloadRequest) (*AppInstance, *ApiEr
 	RegisterAppInstanceEndpoints(resp)
 	return resp, nil, nil
 }
+
+func (e *AppInstance) ReloadE(ro *AppInstanceReloadRequest) (*AppInstance, error) {
+	resp, apierr, err := e.Reload(ro)
+	return resp, AsError(apierr, err)
+}
@@ -64,6 +64,11 @@ func (e *AppTemplates) Create(ro *AppTemplatesCreateRequest) (*AppTemplate, *Api
 	return resp, nil, nil
 }
 
+func (e *AppTemplates) CreateE(ro *AppTemplatesCreateRequest) (*AppTemplate, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AppTemplatesListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -93,6 +98,11 @@ func (e *AppTemplates) List(ro *AppTemplatesListRequest) ([]*AppTemplate, *ApiEr
 	return resp, nil, nil
 }
 
+func (e *AppTemplates) ListE(ro *AppTemplatesListRequest) ([]*AppTemplate, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AppTemplatesGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Name string          `json:"-"`
@@ -115,6 +125,11 @@ func (e *AppTemplates) Get(ro *AppTemplatesGetRequest) (*AppTemplate, *ApiErrorR
 	return resp, nil, nil
 }
 
+func (e *AppTemplates) GetE(ro *AppTemplatesGetRequest) (*AppTemplate, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AppTemplateSetRequest struct {
 	Ctxt             context.Context    `json:"-"`
 	Descr            string             `json:"descr,omitempty" mapstructure:"descr"`
@@ -140,6 +155,11 @@ func (e *AppTemplate) Set(ro *AppTemplateSetRequest) (*AppTemplate, *ApiErrorRes
 
 }
 
+func (e *AppTemplate) SetE(ro *AppTemplateSetRequest) (*AppTemplate, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AppTemplateDeleteRequest struct {
 	Ctxt  context.Context `json:"-"`
 	Force bool            `json:"force,omitempty" mapstructure:"force"`
@@ -160,3 +180,8 @@ func (e *AppTemplate) Delete(ro *AppTemplateDeleteRequest) (*AppTemplate, *ApiEr
 	RegisterAppTemplateEndpoints(resp)
 	return resp, nil, nil
 }
+
+func (e *AppTemplate) DeleteE(ro *AppTemplateDeleteRequest) (*AppTemplate, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
@@ -0,0 +1,185 @@
+package dsdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DefaultBatchParallelism is how many operations of a batch run at the same
+// time when BatchConfig.Parallelism isn't set
+var DefaultBatchParallelism = 8
+
+// ErrBatchSkipped is the error of the operations a batch didn't start because
+// an earlier one failed with BatchConfig.StopOnError set
+var ErrBatchSkipped = errors.New("batch operation skipped")
+
+// BatchOp is a single operation of a batch, eg. a closure calling
+// Initiators.CreateE with ctxt.  Its result ends up in BatchResult.Value
+type BatchOp func(ctxt context.Context) (interface{}, error)
+
+// BatchConfig describes how RunBatch runs the operations of a batch
+type BatchConfig struct {
+	// Parallelism is how many operations run at the same time, defaults to
+	// DefaultBatchParallelism
+	Parallelism int
+	// Rate limits the operations started per second on average, with bursts
+	// of up to Burst operations.  Operations start as fast as Parallelism
+	// allows when 0.  The limits of the connection still apply to the
+	// requests the operations make
+	Rate  float64
+	Burst int
+	// StopOnError stops starting operations once one fails.  Operations
+	// already running are left to finish, cancelling a create or a delete
+	// midway would leave its outcome unknown
+	StopOnError bool
+}
+
+// BatchResult is the outcome of an operation of a batch
+type BatchResult struct {
+	// Index is the position of the operation in the batch
+	Index int
+	Value interface{}
+	// Err is ErrBatchSkipped, or the error of the context of the batch, for
+	// operations that weren't started
+	Err error
+}
+
+// BatchError is returned by RunBatch when some operations failed or were
+// skipped.  It matches the errors of every one of them, so
+// errors.Is(err, ErrNotFound) reports whether any operation got a 404
+type BatchError struct {
+	// Failed is the number of operations with an error, skipped ones included
+	Failed int
+	Total  int
+	// First is the error of the first operation that failed
+	First error
+
+	errs []error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d batch operations failed, first error: %s", e.Failed, e.Total, e.First)
+}
+
+func (e *BatchError) Is(target error) bool {
+	for _, err := range e.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.First
+}
+
+// RunBatch runs ops with bounded parallelism and returns a result for each of
+// them, in the order of ops.  The error is a *BatchError if any operation
+// failed or wasn't started
+//
+//	ops := make([]dsdk.BatchOp, len(iqns))
+//	for i, iqn := range iqns {
+//		iqn := iqn
+//		ops[i] = func(ctxt context.Context) (interface{}, error) {
+//			return sdk.Initiators.CreateE(&dsdk.InitiatorsCreateRequest{Ctxt: ctxt, Id: iqn})
+//		}
+//	}
+//	results, err := dsdk.RunBatch(sdk.NewContext(), dsdk.BatchConfig{Parallelism: 4}, ops)
+func RunBatch(ctxt context.Context, conf BatchConfig, ops []BatchOp) ([]BatchResult, error) {
+	results := make([]BatchResult, len(ops))
+	for i := range results {
+		results[i].Index = i
+	}
+	if len(ops) == 0 {
+		return results, nil
+	}
+	workers := conf.Parallelism
+	if workers <= 0 {
+		workers = DefaultBatchParallelism
+	}
+	if workers > len(ops) {
+		workers = len(ops)
+	}
+	var bucket *tokenBucket
+	if conf.Rate > 0 {
+		bucket = newTokenBucket(conf.Rate, conf.Burst)
+	}
+
+	// feeding stops when the batch fails with StopOnError or when ctxt is
+	// done, the operations themselves run with ctxt
+	feedCtxt, stop := context.WithCancel(ctxt)
+	defer stop()
+	var (
+		m     sync.Mutex
+		first error
+		wg    sync.WaitGroup
+	)
+	next := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				v, err := ops[i](ctxt)
+				results[i].Value, results[i].Err = v, err
+				if err == nil {
+					continue
+				}
+				m.Lock()
+				if first == nil {
+					first = err
+				}
+				m.Unlock()
+				if conf.StopOnError {
+					stop()
+				}
+			}
+		}()
+	}
+
+	started := 0
+feed:
+	for ; started < len(ops); started++ {
+		if bucket != nil {
+			if err := bucket.wait(feedCtxt); err != nil {
+				break
+			}
+		}
+		if feedCtxt.Err() != nil {
+			break
+		}
+		select {
+		case next <- started:
+		case <-feedCtxt.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	skipped := ErrBatchSkipped
+	if err := ctxt.Err(); err != nil {
+		skipped = err
+	}
+	for i := started; i < len(ops); i++ {
+		results[i].Err = skipped
+	}
+	if first == nil && started < len(ops) {
+		first = skipped
+	}
+
+	berr := &BatchError{Total: len(ops), First: first}
+	for _, r := range results {
+		if r.Err != nil {
+			berr.Failed++
+			berr.errs = append(berr.errs, r.Err)
+		}
+	}
+	if berr.Failed == 0 {
+		return results, nil
+	}
+	return results, berr
+}
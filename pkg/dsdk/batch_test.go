@@ -0,0 +1,94 @@
+package dsdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunBatch(t *testing.T) {
+	var (
+		m        sync.Mutex
+		running  int
+		maxSeen  int
+		ops      []BatchOp
+		notFound = fmt.Errorf("snapshot 3: %w", ErrNotFound)
+	)
+	for i := 0; i < 10; i++ {
+		i := i
+		ops = append(ops, func(ctxt context.Context) (interface{}, error) {
+			m.Lock()
+			running++
+			if running > maxSeen {
+				maxSeen = running
+			}
+			m.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			m.Lock()
+			running--
+			m.Unlock()
+			if i == 3 {
+				return nil, notFound
+			}
+			return i * i, nil
+		})
+	}
+
+	// continue on error
+	results, err := RunBatch(context.Background(), BatchConfig{Parallelism: 3}, ops)
+	if maxSeen != 3 {
+		t.Errorf("expected 3 operations at a time, got %d", maxSeen)
+	}
+	berr, ok := err.(*BatchError)
+	if !ok || berr.Failed != 1 || berr.Total != 10 || berr.First != notFound || !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unexpected error %#v", err)
+	}
+	for i, r := range results {
+		if r.Index != i {
+			t.Errorf("result %d has index %d", i, r.Index)
+		}
+		if i != 3 && (r.Err != nil || r.Value.(int) != i*i) {
+			t.Errorf("unexpected result %d: %v, %v", i, r.Value, r.Err)
+		}
+	}
+
+	// stop on first error
+	results, err = RunBatch(context.Background(), BatchConfig{Parallelism: 1, StopOnError: true}, ops)
+	if !errors.Is(err, ErrNotFound) || !errors.Is(err, ErrBatchSkipped) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for i, r := range results {
+		switch {
+		case i < 3 && r.Err != nil:
+			t.Errorf("expected operation %d to succeed, got %v", i, r.Err)
+		case i == 3 && r.Err != notFound:
+			t.Errorf("expected operation 3 to fail, got %v", r.Err)
+		case i > 3 && r.Err != ErrBatchSkipped:
+			t.Errorf("expected operation %d to be skipped, got %v", i, r.Err)
+		}
+	}
+
+	// rate limit, the first two are a burst and the other two wait 50ms each
+	start := time.Now()
+	if _, err = RunBatch(context.Background(), BatchConfig{Rate: 20, Burst: 2}, ops[4:8]); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("expected the batch to be rate limited, took %s", d)
+	}
+
+	// a cancelled batch doesn't start anything
+	ctxt, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err = RunBatch(ctxt, BatchConfig{}, ops[:2])
+	if !errors.Is(err, context.Canceled) || results[0].Err != context.Canceled || results[1].Value != nil {
+		t.Errorf("unexpected results %#v, %v", results, err)
+	}
+
+	if results, err = RunBatch(context.Background(), BatchConfig{}, nil); err != nil || len(results) != 0 {
+		t.Errorf("unexpected results for an empty batch %#v, %v", results, err)
+	}
+}
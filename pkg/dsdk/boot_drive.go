@@ -55,6 +55,11 @@ func (e *BootDrives) List(ro *BootDrivesListRequest) ([]*BootDrive, *ApiErrorRes
 	return resp, nil, nil
 }
 
+func (e *BootDrives) ListE(ro *BootDrivesListRequest) ([]*BootDrive, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type BootDrivesGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Id   string          `json:"-"`
@@ -75,3 +80,8 @@ func (e *BootDrives) Get(ro *BootDrivesGetRequest) (*BootDrive, *ApiErrorRespons
 	}
 	return resp, nil, nil
 }
+
+func (e *BootDrives) GetE(ro *BootDrivesGetRequest) (*BootDrive, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
@@ -0,0 +1,245 @@
+package dsdk
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without making a request while the circuit
+// breaker of a connection, or of the route of the request, is open.  It
+// matches ErrUnavailable as well, see CircuitOpenError
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitOpenError is the error returned for requests rejected by an open
+// circuit breaker
+type CircuitOpenError struct {
+	// Route is the canonical route of the open circuit, "" for the circuit of
+	// the whole connection
+	Route string
+	// Until is when the circuit lets a request through to probe the cluster
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.Route == "" {
+		return fmt.Sprintf("%s until %s", ErrCircuitOpen, e.Until.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%s for %s until %s", ErrCircuitOpen, e.Route, e.Until.Format(time.RFC3339))
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen || target == ErrUnavailable
+}
+
+// CircuitState is the state of a circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets every request through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects every request with ErrCircuitOpen
+	CircuitOpen
+	// CircuitHalfOpen lets a single request through to probe the cluster
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+var (
+	// DefaultCircuitThreshold is the number of consecutive failures opening a
+	// circuit when CircuitBreakerConfig.Threshold isn't set
+	DefaultCircuitThreshold = 5
+	// DefaultCircuitCooldown is how long a circuit stays open when
+	// CircuitBreakerConfig.Cooldown isn't set
+	DefaultCircuitCooldown = 30 * time.Second
+)
+
+// CircuitBreakerConfig describes when the circuit breaker of a connection
+// stops sending requests to the cluster
+type CircuitBreakerConfig struct {
+	// Threshold is the number of consecutive 503s or connection errors that
+	// open the circuit, defaults to DefaultCircuitThreshold
+	Threshold int
+	// Cooldown is how long the circuit stays open before a request is let
+	// through to probe the cluster, defaults to DefaultCircuitCooldown
+	Cooldown time.Duration
+	// PerRoute keeps a circuit per canonical route in addition to the one for
+	// the whole connection, so a failing route doesn't affect the others
+	// until the connection wide threshold is reached too
+	PerRoute bool
+	// OnStateChange is called whenever a circuit changes state.  route is the
+	// canonical route of the circuit, "" for the one of the whole connection
+	OnStateChange func(route string, from, to CircuitState)
+}
+
+// circuit is the state of a single circuit breaker
+type circuit struct {
+	state    CircuitState
+	failures int
+	until    time.Time
+	// probing is set while the request probing a half-open circuit is in
+	// flight
+	probing bool
+}
+
+type stateChange struct {
+	route    string
+	from, to CircuitState
+}
+
+type circuitBreaker struct {
+	m      sync.Mutex
+	conf   CircuitBreakerConfig
+	global circuit
+	routes map[string]*circuit
+}
+
+// SetCircuitBreaker makes the connection fail fast with ErrCircuitOpen after
+// conf.Threshold consecutive 503s or connection errors, instead of retrying
+// against a cluster that is down.  It should be called before the connection
+// is shared between goroutines
+func (c *ApiConnection) SetCircuitBreaker(conf CircuitBreakerConfig) {
+	if conf.Threshold <= 0 {
+		conf.Threshold = DefaultCircuitThreshold
+	}
+	if conf.Cooldown <= 0 {
+		conf.Cooldown = DefaultCircuitCooldown
+	}
+	c.breaker = &circuitBreaker{conf: conf, routes: map[string]*circuit{}}
+}
+
+// CircuitState returns the state of the circuit of route, or of the whole
+// connection if route is "".  Routes are canonicalized like with
+// SetRouteRateLimit
+func (c *ApiConnection) CircuitState(route string) CircuitState {
+	b := c.breaker
+	if b == nil {
+		return CircuitClosed
+	}
+	b.m.Lock()
+	defer b.m.Unlock()
+	if route == "" {
+		return b.global.current(time.Now())
+	}
+	if cr, ok := b.routes[c.canonicalRoute(route)]; ok {
+		return cr.current(time.Now())
+	}
+	return CircuitClosed
+}
+
+// current is the state of the circuit, reporting an open circuit whose
+// cooldown is over as half-open
+func (cr *circuit) current(now time.Time) CircuitState {
+	if cr.state == CircuitOpen && !now.Before(cr.until) {
+		return CircuitHalfOpen
+	}
+	return cr.state
+}
+
+// allow reports whether a request may be sent through the circuit, turning an
+// open circuit half-open once its cooldown is over
+func (cr *circuit) allow(route string, now time.Time, changes []stateChange) ([]stateChange, error) {
+	switch cr.state {
+	case CircuitOpen:
+		if now.Before(cr.until) {
+			return changes, &CircuitOpenError{Route: route, Until: cr.until}
+		}
+		cr.state, cr.probing = CircuitHalfOpen, true
+		return append(changes, stateChange{route, CircuitOpen, CircuitHalfOpen}), nil
+	case CircuitHalfOpen:
+		if cr.probing {
+			return changes, &CircuitOpenError{Route: route, Until: cr.until}
+		}
+		cr.probing = true
+	}
+	return changes, nil
+}
+
+// record updates the circuit with the outcome of a request it allowed.  A
+// request that was neither a success nor a failure, eg. because it was
+// cancelled, only frees up the probe of a half-open circuit
+func (cr *circuit) record(route string, now time.Time, failed, aborted bool, threshold int, cooldown time.Duration, changes []stateChange) []stateChange {
+	if aborted {
+		cr.probing = false
+		return changes
+	}
+	from := cr.state
+	if !failed {
+		cr.state, cr.failures, cr.probing = CircuitClosed, 0, false
+	} else {
+		cr.failures++
+		if cr.state == CircuitHalfOpen || cr.failures >= threshold {
+			cr.state, cr.until, cr.probing = CircuitOpen, now.Add(cooldown), false
+		}
+	}
+	if from != cr.state {
+		changes = append(changes, stateChange{route, from, cr.state})
+	}
+	return changes
+}
+
+// circuitAllow returns an error if the request to route must not be sent
+func (c *ApiConnection) circuitAllow(route string) error {
+	b := c.breaker
+	if b == nil {
+		return nil
+	}
+	now := time.Now()
+	b.m.Lock()
+	changes, err := b.global.allow("", now, nil)
+	if err == nil && b.conf.PerRoute {
+		cr, ok := b.routes[route]
+		if !ok {
+			cr = &circuit{}
+			b.routes[route] = cr
+		}
+		if changes, err = cr.allow(route, now, changes); err != nil {
+			// the connection wide circuit let this one through for nothing
+			b.global.probing = false
+		}
+	}
+	b.m.Unlock()
+	c.circuitChanged(changes)
+	return err
+}
+
+// circuitRecord reports the outcome of a request allowed by circuitAllow
+func (c *ApiConnection) circuitRecord(route string, failed, aborted bool) {
+	b := c.breaker
+	if b == nil {
+		return
+	}
+	now := time.Now()
+	b.m.Lock()
+	changes := b.global.record("", now, failed, aborted, b.conf.Threshold, b.conf.Cooldown, nil)
+	if cr, ok := b.routes[route]; ok && b.conf.PerRoute {
+		changes = cr.record(route, now, failed, aborted, b.conf.Threshold, b.conf.Cooldown, changes)
+	}
+	b.m.Unlock()
+	c.circuitChanged(changes)
+}
+
+func (c *ApiConnection) circuitChanged(changes []stateChange) {
+	for _, ch := range changes {
+		name := ch.route
+		if name == "" {
+			name = "connection"
+		}
+		c.log(nil).Warnf("Datera SDK circuit breaker for %s changed from %s to %s", name, ch.from, ch.to)
+		if c.breaker.conf.OnStateChange != nil {
+			c.breaker.conf.OnStateChange(ch.route, ch.from, ch.to)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package dsdk
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var (
+		m       sync.Mutex
+		changes []string
+	)
+	c := &ApiConnection{apiVersion: "2.2", m: &sync.RWMutex{}}
+	c.SetCircuitBreaker(CircuitBreakerConfig{
+		Threshold: 2,
+		Cooldown:  20 * time.Millisecond,
+		PerRoute:  true,
+		OnStateChange: func(route string, from, to CircuitState) {
+			m.Lock()
+			defer m.Unlock()
+			changes = append(changes, route+" "+from.String()+" -> "+to.String())
+		},
+	})
+	route := c.canonicalRoute("app_instances/my-ai")
+	other := c.canonicalRoute("system")
+
+	for i := 0; i < 2; i++ {
+		if err := c.circuitAllow(route); err != nil {
+			t.Fatalf("expected the request to be allowed, got %s", err)
+		}
+		c.circuitRecord(route, true, false)
+	}
+	err := c.circuitAllow(other)
+	if !errors.Is(err, ErrCircuitOpen) || !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if s := c.CircuitState("app_instances/other-ai"); s != CircuitOpen {
+		t.Errorf("expected the route to be open, got %s", s)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if s := c.CircuitState(""); s != CircuitHalfOpen {
+		t.Errorf("expected the connection to be half-open, got %s", s)
+	}
+	// a single probe is let through
+	if err := c.circuitAllow(route); err != nil {
+		t.Fatalf("expected the probe to be allowed, got %s", err)
+	}
+	if err := c.circuitAllow(route); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected a second probe to be rejected, got %v", err)
+	}
+	// a cancelled probe doesn't count, the next request probes instead
+	c.circuitRecord(route, false, true)
+	if err := c.circuitAllow(route); err != nil {
+		t.Fatalf("expected another probe to be allowed, got %s", err)
+	}
+	c.circuitRecord(route, false, false)
+	if s := c.CircuitState(""); s != CircuitClosed {
+		t.Errorf("expected the connection to be closed, got %s", s)
+	}
+
+	want := []string{
+		" closed -> open",
+		route + " closed -> open",
+		" open -> half-open",
+		route + " open -> half-open",
+		" half-open -> closed",
+		route + " half-open -> closed",
+	}
+	m.Lock()
+	defer m.Unlock()
+	if len(changes) != len(want) {
+		t.Fatalf("expected %v, got %v", want, changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, changes)
+			break
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package dsdk
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	greq "github.com/levigross/grequests"
+)
+
+// DefaultCacheMaxEntries is how many responses a cache keeps when
+// CacheConfig.MaxEntries isn't set
+var DefaultCacheMaxEntries = 1000
+
+// CacheConfig describes which GET responses an ApiConnection caches and for
+// how long
+type CacheConfig struct {
+	// TTL is how long responses are cached for routes without their own TTL.
+	// When 0 only routes in RouteTTLs are cached
+	TTL time.Duration
+	// RouteTTLs overrides TTL per route, eg. "app_instances/:id" or "system".
+	// A TTL of 0 disables caching for the route
+	RouteTTLs map[string]time.Duration
+	// MaxEntries caps the number of cached responses, defaults to
+	// DefaultCacheMaxEntries
+	MaxEntries int
+}
+
+// WithNoCache returns a context whose requests skip the cache of the
+// ApiConnection.  Their responses still refresh it
+func WithNoCache(ctxt context.Context) context.Context {
+	return context.WithValue(ctxt, noCacheCtxKey, true)
+}
+
+func noCache(ctxt context.Context) bool {
+	n, _ := ctxt.Value(noCacheCtxKey).(bool)
+	return n
+}
+
+type cacheEntry struct {
+	path    string
+	value   interface{}
+	expires time.Time
+}
+
+// responseCache holds copies of decoded GET responses
+type responseCache struct {
+	m          sync.Mutex
+	ttl        time.Duration
+	routes     map[string]time.Duration
+	maxEntries int
+	entries    map[string]*cacheEntry
+	// gen is bumped by every invalidation.  invalidated holds the generation
+	// of the last invalidation of a path while reads started before it are in
+	// flight, and reads counts the reads in flight by starting generation
+	gen         uint64
+	invalidated map[string]uint64
+	reads       map[uint64]int
+}
+
+// SetCache caches successful Get and GetList responses, keyed by tenant, path
+// and params, as described by conf.  Put, Post and Delete invalidate the
+// responses for their path, its parents and its children.  It should be
+// called before the connection is shared between goroutines
+func (c *ApiConnection) SetCache(conf CacheConfig) {
+	rc := &responseCache{
+		ttl:        conf.TTL,
+		routes:     map[string]time.Duration{},
+		maxEntries: conf.MaxEntries,
+		entries:    map[string]*cacheEntry{},
+
+		invalidated: map[string]uint64{},
+		reads:       map[uint64]int{},
+	}
+	if rc.maxEntries <= 0 {
+		rc.maxEntries = DefaultCacheMaxEntries
+	}
+	for route, ttl := range conf.RouteTTLs {
+		rc.routes[c.canonicalRoute(route)] = ttl
+	}
+	c.cache = rc
+}
+
+// DisableCache drops every cached response and stops caching.  It should be
+// called before the connection is shared between goroutines
+func (c *ApiConnection) DisableCache() {
+	c.cache = nil
+}
+
+// cachePath normalizes url so "/app_instances/" and "app_instances" are the
+// same path
+func cachePath(url string) string {
+	return strings.Trim(path.Clean("/"+url), "/")
+}
+
+// cacheKey returns the key of a request, or "" if it shouldn't be cached
+func (c *ApiConnection) cacheKey(ctxt context.Context, url string, ro *greq.RequestOptions) string {
+	// c is nil for endpoint calls whose context has no connection
+	if c == nil || c.cache == nil || c.cacheTTL(url) <= 0 {
+		return ""
+	}
+	return c.requestKey(ctxt, url, ro)
+}
+
+func (c *ApiConnection) cacheTTL(url string) time.Duration {
+	if ttl, ok := c.cache.routes[c.canonicalRoute(cachePath(url))]; ok {
+		return ttl
+	}
+	return c.cache.ttl
+}
+
+// cacheGet returns a copy of the response cached for key
+func (c *ApiConnection) cacheGet(ctxt context.Context, key string) (interface{}, bool) {
+	if key == "" || noCache(ctxt) {
+		return nil, false
+	}
+	rc := c.cache
+	rc.m.Lock()
+	defer rc.m.Unlock()
+	e, ok := rc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(rc.entries, key)
+		return nil, false
+	}
+	return copyResponse(e.value), true
+}
+
+// cacheBegin is called before the request whose response will be stored for
+// key is sent.  The returned generation must be passed to cachePut and
+// cacheEnd
+func (c *ApiConnection) cacheBegin(key string) uint64 {
+	if key == "" {
+		return 0
+	}
+	rc := c.cache
+	rc.m.Lock()
+	defer rc.m.Unlock()
+	rc.reads[rc.gen]++
+	return rc.gen
+}
+
+// cacheEnd is called once the request started at generation start is done
+func (c *ApiConnection) cacheEnd(key string, start uint64) {
+	if key == "" {
+		return
+	}
+	rc := c.cache
+	rc.m.Lock()
+	defer rc.m.Unlock()
+	if rc.reads[start]--; rc.reads[start] <= 0 {
+		delete(rc.reads, start)
+	}
+	// invalidations can only make reads started before them stale
+	oldest := rc.gen
+	for gen := range rc.reads {
+		if gen < oldest {
+			oldest = gen
+		}
+	}
+	for p, gen := range rc.invalidated {
+		if gen <= oldest {
+			delete(rc.invalidated, p)
+		}
+	}
+}
+
+// cachePut stores a copy of value for key, unless url was invalidated since
+// the request started at generation start, in which case value may predate
+// the mutation
+func (c *ApiConnection) cachePut(key, url string, value interface{}, start uint64) {
+	if key == "" {
+		return
+	}
+	rc := c.cache
+	now := time.Now()
+	e := &cacheEntry{path: cachePath(url), value: copyResponse(value), expires: now.Add(c.cacheTTL(url))}
+	rc.m.Lock()
+	defer rc.m.Unlock()
+	for p, gen := range rc.invalidated {
+		if gen > start && (e.path == p || isParentPath(e.path, p) || isParentPath(p, e.path)) {
+			return
+		}
+	}
+	if _, ok := rc.entries[key]; !ok && len(rc.entries) >= rc.maxEntries {
+		for k, old := range rc.entries {
+			if now.After(old.expires) {
+				delete(rc.entries, k)
+			}
+		}
+		// still full, make room by dropping an arbitrary entry
+		for k := range rc.entries {
+			if len(rc.entries) < rc.maxEntries {
+				break
+			}
+			delete(rc.entries, k)
+		}
+	}
+	rc.entries[key] = e
+}
+
+// cacheInvalidate drops the responses for url, its parents and its children
+// in every tenant
+func (c *ApiConnection) cacheInvalidate(url string) {
+	if c.cache == nil {
+		return
+	}
+	rc := c.cache
+	p := cachePath(url)
+	rc.m.Lock()
+	defer rc.m.Unlock()
+	rc.gen++
+	if len(rc.reads) > 0 {
+		rc.invalidated[p] = rc.gen
+	}
+	for k, e := range rc.entries {
+		if e.path == p || isParentPath(e.path, p) || isParentPath(p, e.path) {
+			delete(rc.entries, k)
+		}
+	}
+}
+
+// isParentPath reports whether parent is an ancestor of p
+func isParentPath(parent, p string) bool {
+	return parent == "" || strings.HasPrefix(p, parent+"/")
+}
+
+// copyResponse deep copies a decoded response so callers can't modify what
+// is cached or handed to other callers
+func copyResponse(v interface{}) interface{} {
+	switch t := v.(type) {
+	case *ApiOuter:
+		r := *t
+		r.Data, _ = copyValue(t.Data).(map[string]interface{})
+		r.Metadata, _ = copyValue(t.Metadata).(map[string]interface{})
+		return &r
+	case *ApiListOuter:
+		r := *t
+		r.Data, _ = copyValue(t.Data).([]interface{})
+		r.Metadata, _ = copyValue(t.Metadata).(map[string]interface{})
+		return &r
+	}
+	return copyValue(v)
+}
+
+func copyValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if t == nil {
+			return t
+		}
+		r := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			r[k] = copyValue(val)
+		}
+		return r
+	case []interface{}:
+		if t == nil {
+			return t
+		}
+		r := make([]interface{}, len(t))
+		for i, val := range t {
+			r[i] = copyValue(val)
+		}
+		return r
+	}
+	return v
+}
@@ -0,0 +1,91 @@
+package dsdk
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	greq "github.com/levigross/grequests"
+)
+
+func TestResponseCache(t *testing.T) {
+	c := &ApiConnection{apiVersion: "2.2", tenant: "/root", m: &sync.RWMutex{}}
+	c.SetCache(CacheConfig{
+		TTL:       time.Minute,
+		RouteTTLs: map[string]time.Duration{"system": 0},
+	})
+	ctxt := context.Background()
+	if key := c.cacheKey(ctxt, "system", nil); key != "" {
+		t.Errorf("expected system not to be cached, got %s", key)
+	}
+
+	ro := &greq.RequestOptions{Params: map[string]string{"limit": "10", "filter": "x"}}
+	key := c.cacheKey(ctxt, "/app_instances/", ro)
+	if key != "/root|app_instances?filter=x&limit=10" {
+		t.Errorf("unexpected key %s", key)
+	}
+	if other := c.cacheKey(WithTenant(ctxt, "/root/foo"), "app_instances", ro); other == key {
+		t.Error("expected tenants to have different keys")
+	}
+
+	c.cachePut(key, "app_instances", &ApiListOuter{Data: []interface{}{map[string]interface{}{"name": "ai"}}}, 0)
+	aiKey := c.cacheKey(ctxt, "app_instances/ai", nil)
+	c.cachePut(aiKey, "app_instances/ai", &ApiOuter{Data: map[string]interface{}{"name": "ai"}}, 0)
+	siKey := c.cacheKey(ctxt, "app_instances/ai/storage_instances/si", nil)
+	c.cachePut(siKey, "app_instances/ai/storage_instances/si", &ApiOuter{}, 0)
+	otherKey := c.cacheKey(ctxt, "app_instances/other", nil)
+	c.cachePut(otherKey, "app_instances/other", &ApiOuter{}, 0)
+
+	// callers get their own copy
+	v, ok := c.cacheGet(ctxt, aiKey)
+	if !ok {
+		t.Fatal("expected a cached response")
+	}
+	v.(*ApiOuter).Data["name"] = "changed"
+	v, _ = c.cacheGet(ctxt, aiKey)
+	if name := v.(*ApiOuter).Data["name"]; name != "ai" {
+		t.Errorf("expected the cached response to be unchanged, got %s", name)
+	}
+	if _, ok := c.cacheGet(WithNoCache(ctxt), aiKey); ok {
+		t.Error("expected WithNoCache to skip the cache")
+	}
+
+	// the path, its parents and its children are invalidated
+	c.cacheInvalidate("/app_instances/ai")
+	for _, k := range []string{key, aiKey, siKey} {
+		if _, ok := c.cacheGet(ctxt, k); ok {
+			t.Errorf("expected %s to be invalidated", k)
+		}
+	}
+	if _, ok := c.cacheGet(ctxt, otherKey); !ok {
+		t.Error("expected siblings to stay cached")
+	}
+
+	// a read that started before a mutation of its path, or of a parent,
+	// doesn't cache what it got
+	start := c.cacheBegin(aiKey)
+	c.cacheInvalidate("app_instances")
+	c.cachePut(aiKey, "app_instances/ai", &ApiOuter{}, start)
+	c.cacheEnd(aiKey, start)
+	if _, ok := c.cacheGet(ctxt, aiKey); ok {
+		t.Error("expected a read started before the invalidation not to be cached")
+	}
+	if n := len(c.cache.invalidated); n != 0 {
+		t.Errorf("expected invalidations to be forgotten once no read predates them, got %d", n)
+	}
+	start = c.cacheBegin(aiKey)
+	c.cacheInvalidate("app_instances/other")
+	c.cachePut(aiKey, "app_instances/ai", &ApiOuter{}, start)
+	c.cacheEnd(aiKey, start)
+	if _, ok := c.cacheGet(ctxt, aiKey); !ok {
+		t.Error("expected invalidating a sibling not to affect the read")
+	}
+
+	// nothing is cached without SetCache
+	c.DisableCache()
+	if key := c.cacheKey(ctxt, "app_instances", nil); key != "" {
+		t.Errorf("expected no key, got %s", key)
+	}
+	c.cacheInvalidate("app_instances")
+}
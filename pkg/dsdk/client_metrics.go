@@ -0,0 +1,244 @@
+package dsdk
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MetricsRecorder receives measurements of the requests made by an
+// ApiConnection.  ClientMetrics is a dependency free implementation which
+// can be scraped by Prometheus, but any metrics library can be plugged in.
+type MetricsRecorder interface {
+	// ObserveRequest is called once per HTTP request.  code is 0 when no
+	// response was received
+	ObserveRequest(method, route string, code int, d time.Duration)
+	// IncRetries is called every time a failed request is attempted again
+	IncRetries(method, route string)
+	// IncReauthentications is called when a request got a 401 and the
+	// connection logs in again before retrying it
+	IncReauthentications()
+	// IncLogins is called after every login request
+	IncLogins(success bool)
+	// AddInFlight is called with 1 when a request is sent and -1 when it is done
+	AddInFlight(delta int)
+}
+
+// DefaultDurationBuckets are the upper bounds in seconds of the request
+// duration histogram buckets
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+// SetMetrics enables recording metrics for every request made with this
+// connection.  It should be called before the connection is shared between
+// goroutines
+func (c *ApiConnection) SetMetrics(m MetricsRecorder) {
+	c.metrics = m
+}
+
+type routeLabels struct {
+	method string
+	route  string
+}
+
+type responseLabels struct {
+	routeLabels
+	code int
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// ClientMetrics is an in-memory MetricsRecorder.  It implements http.Handler
+// serving the metrics in the Prometheus text exposition format so it can be
+// mounted on an existing metrics endpoint
+type ClientMetrics struct {
+	namespace string
+	buckets   []float64
+
+	m         sync.Mutex
+	durations map[routeLabels]*histogram
+	responses map[responseLabels]uint64
+	retries   map[routeLabels]uint64
+	reauths   uint64
+	logins    map[bool]uint64
+	inFlight  int64
+}
+
+// NewClientMetrics returns a ClientMetrics whose metric names are prefixed
+// with namespace, "datera_sdk" if empty.  buckets default to
+// DefaultDurationBuckets
+func NewClientMetrics(namespace string, buckets ...float64) *ClientMetrics {
+	if namespace == "" {
+		namespace = "datera_sdk"
+	}
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+	b := append([]float64{}, buckets...)
+	sort.Float64s(b)
+	return &ClientMetrics{
+		namespace: namespace,
+		buckets:   b,
+		durations: map[routeLabels]*histogram{},
+		responses: map[responseLabels]uint64{},
+		retries:   map[routeLabels]uint64{},
+		logins:    map[bool]uint64{},
+	}
+}
+
+func (m *ClientMetrics) ObserveRequest(method, route string, code int, d time.Duration) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	l := routeLabels{method: method, route: route}
+	h, ok := m.durations[l]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.durations[l] = h
+	}
+	secs := d.Seconds()
+	for i, b := range m.buckets {
+		if secs <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += secs
+	m.responses[responseLabels{routeLabels: l, code: code}]++
+}
+
+func (m *ClientMetrics) IncRetries(method, route string) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.retries[routeLabels{method: method, route: route}]++
+}
+
+func (m *ClientMetrics) IncReauthentications() {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.reauths++
+}
+
+func (m *ClientMetrics) IncLogins(success bool) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.logins[success]++
+}
+
+func (m *ClientMetrics) AddInFlight(delta int) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.inFlight += int64(delta)
+}
+
+func (m *ClientMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := m.WriteTo(w); err != nil {
+		defaultLog().Errorf("Failed writing metrics: %s", err)
+	}
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format
+func (m *ClientMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	ns := m.namespace
+
+	// label values are only methods and canonical routes so %q quoting is
+	// compatible with the exposition format
+	name := ns + "_request_duration_seconds"
+	cw.printf("# HELP %s Duration of requests to the Datera API.\n# TYPE %s histogram\n", name, name)
+	for _, l := range sortedRouteLabels(m.durations) {
+		h := m.durations[l]
+		base := fmt.Sprintf("method=%q,route=%q", l.method, l.route)
+		for i, b := range m.buckets {
+			cw.printf("%s_bucket{%s,le=\"%s\"} %d\n", name, base, strconv.FormatFloat(b, 'g', -1, 64), h.counts[i])
+		}
+		cw.printf("%s_bucket{%s,le=\"+Inf\"} %d\n", name, base, h.count)
+		cw.printf("%s_sum{%s} %s\n", name, base, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		cw.printf("%s_count{%s} %d\n", name, base, h.count)
+	}
+
+	name = ns + "_responses_total"
+	cw.printf("# HELP %s Responses received from the Datera API by status code, 0 if none was received.\n# TYPE %s counter\n", name, name)
+	rkeys := make([]responseLabels, 0, len(m.responses))
+	for k := range m.responses {
+		rkeys = append(rkeys, k)
+	}
+	sort.Slice(rkeys, func(i, j int) bool {
+		if rkeys[i].routeLabels != rkeys[j].routeLabels {
+			return lessRouteLabels(rkeys[i].routeLabels, rkeys[j].routeLabels)
+		}
+		return rkeys[i].code < rkeys[j].code
+	})
+	for _, k := range rkeys {
+		cw.printf("%s{method=%q,route=%q,code=\"%d\"} %d\n", name, k.method, k.route, k.code, m.responses[k])
+	}
+
+	name = ns + "_retries_total"
+	cw.printf("# HELP %s Requests attempted again after a failure.\n# TYPE %s counter\n", name, name)
+	for _, l := range sortedRouteLabels(m.retries) {
+		cw.printf("%s{method=%q,route=%q} %d\n", name, l.method, l.route, m.retries[l])
+	}
+
+	name = ns + "_reauthentications_total"
+	cw.printf("# HELP %s Logins caused by an expired session.\n# TYPE %s counter\n%s %d\n", name, name, name, m.reauths)
+
+	name = ns + "_logins_total"
+	cw.printf("# HELP %s Login requests by result.\n# TYPE %s counter\n", name, name)
+	cw.printf("%s{result=\"failure\"} %d\n%s{result=\"success\"} %d\n", name, m.logins[false], name, m.logins[true])
+
+	name = ns + "_requests_in_flight"
+	cw.printf("# HELP %s Requests currently waiting on the Datera API.\n# TYPE %s gauge\n%s %d\n", name, name, name, m.inFlight)
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+func sortedRouteLabels(m interface{}) []routeLabels {
+	keys := []routeLabels{}
+	switch t := m.(type) {
+	case map[routeLabels]*histogram:
+		for k := range t {
+			keys = append(keys, k)
+		}
+	case map[routeLabels]uint64:
+		for k := range t {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return lessRouteLabels(keys[i], keys[j]) })
+	return keys
+}
+
+func lessRouteLabels(a, b routeLabels) bool {
+	if a.route != b.route {
+		return a.route < b.route
+	}
+	return a.method < b.method
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) printf(format string, args ...interface{}) {
+	if c.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(c.w, format, args...)
+	c.n += int64(n)
+	c.err = err
+}
@@ -2,7 +2,6 @@ package dsdk
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,12 +11,12 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	udc "github.com/Datera/go-udc/pkg/udc"
 	uuid "github.com/google/uuid"
 	greq "github.com/levigross/grequests"
-	log "github.com/sirupsen/logrus"
 )
 
 var (
@@ -25,18 +24,12 @@ var (
 	ErrRetryTimeout        = errors.New("timeout reached before request completed successfully during retries")
 	InvalidRequest         = 400
 	PermissionDenied       = 401
+	Conflict               = 409
 	Retry503               = 503
 	ConnectionError        = 9998
 	RetryRequestAfterLogin = 9999
-	badStatus              = map[int]error{
-		InvalidRequest:         fmt.Errorf("InvalidRequest"),
-		PermissionDenied:       fmt.Errorf("PermissionDenied"),
-		Retry503:               fmt.Errorf("Retry503"),
-		ConnectionError:        fmt.Errorf("ConnectionError"),
-		RetryRequestAfterLogin: fmt.Errorf("RetryRequestAfterLogin"),
-	}
-	DateraDriver = fmt.Sprintf("Golang-SDK-%s", VERSION)
-	logTraceID   = "trace_id"
+	DateraDriver           = fmt.Sprintf("Golang-SDK-%s", VERSION)
+	logTraceID             = "trace_id"
 )
 
 const (
@@ -46,16 +39,42 @@ const (
 )
 
 type ApiConnection struct {
-	m          *sync.RWMutex
-	username   string
-	password   string
-	apiVersion string
-	tenant     string
-	secure     bool
-	ldap       string
-	apikey     string
-	baseUrl    *url.URL
-	httpClient *http.Client
+	// lastUsed is accessed atomically so it must stay the first field to be
+	// 64-bit aligned on 32-bit platforms
+	lastUsed    int64
+	m           *sync.RWMutex
+	credentials CredentialProvider
+	apiVersion  string
+	tenant      string
+	secure      bool
+	apikey      string
+	mgmtIp      string
+	httpClient  *http.Client
+	retryPolicy RetryPolicy
+	// endpoints are the base urls of every management endpoint of the cluster
+	endpoints    *endpointPool
+	endpointConf EndpointConfig
+	discoverOnce sync.Once
+	limiter      *requestLimiter
+	interceptors []Interceptor
+	tracer       Tracer
+	metrics      MetricsRecorder
+	redactor     *redactor
+	logger       Logger
+	pageWorkers  int
+	cache        *responseCache
+	flight       *flightGroup
+	breaker      *circuitBreaker
+	tokenStore   TokenStore
+	// tokenKey is the TokenStore key of the current apikey
+	tokenKey    string
+	sessionConf SessionConfig
+	// sessionExpires is when the apikey is expected to expire, zero if unknown
+	sessionExpires time.Time
+	refreshTimer   *time.Timer
+	// loginCall is the login in progress, if any, which concurrent callers
+	// wait on instead of logging in themselves
+	loginCall *sessionCall
 }
 
 type ApiErrorResponse struct {
@@ -75,6 +94,8 @@ type ApiErrorResponse struct {
 	Id           int               `json:"api_req_id,omitempty"`
 	TenancyClass string            `json:"tenancy_class,omitempty"`
 	Errors       []string          `json:"errors,omitempty"`
+	// RetryAfter is the delay requested by the server via the Retry-After header
+	RetryAfter time.Duration `json:"-"`
 }
 
 type ApiLogin struct {
@@ -218,24 +239,19 @@ func ListRangeParamsFromMap(m map[string]string) *ListRangeParams {
 	return lp
 }
 
-func init() {
-	// TODO(_alastor_): Disable this and do real certificate verification
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-}
-
-func makeBaseUrl(h, apiv string, secure bool) (*url.URL, error) {
-	h = strings.Trim(h, "/")
+func makeBaseUrl(h, apiv string, secure bool, port int) (*url.URL, error) {
+	h = hostPort(strings.Trim(h, "/"), port)
 	if secure {
-		return url.Parse(fmt.Sprintf("https://%s:7718/v%s", h, apiv))
+		return url.Parse(fmt.Sprintf("https://%s/v%s", h, apiv))
 	}
-	return url.Parse(fmt.Sprintf("http://%s:7717/v%s", h, apiv))
+	return url.Parse(fmt.Sprintf("http://%s/v%s", h, apiv))
 }
 
-func translateErrors(ctxt context.Context, resp *greq.Response, err error) (*ApiErrorResponse, error) {
+func (c *ApiConnection) translateErrors(ctxt context.Context, resp *greq.Response, err error) (*ApiErrorResponse, error) {
 	if err != nil {
-		WithUserFields(ctxt, Log()).Error(err)
+		c.log(ctxt).Errorf("%s", err)
 		if strings.Contains(err.Error(), "connect: connection refused") {
-			return nil, badStatus[ConnectionError]
+			return nil, ErrConnection
 		}
 		return nil, err
 	}
@@ -244,7 +260,7 @@ func translateErrors(ctxt context.Context, resp *greq.Response, err error) (*Api
 		eresp := &ApiErrorResponse{}
 		err := resp.JSON(eresp)
 		if err != nil {
-			WithUserFields(ctxt, Log()).Error(fmt.Sprintf("failed to unmarshal ApiErrorResponse %+v: %v", eresp, err))
+			c.log(ctxt).Errorf("failed to unmarshal ApiErrorResponse %+v: %v", eresp, err)
 		}
 
 		// in some cases (like 503s) the response JSON doesn't contain
@@ -253,7 +269,8 @@ func translateErrors(ctxt context.Context, resp *greq.Response, err error) (*Api
 		if eresp.Http == 0 {
 			eresp.Http = resp.StatusCode
 		}
-		return eresp, badStatus[resp.StatusCode]
+		eresp.RetryAfter = parseRetryAfter(resp.Header)
+		return eresp, NewApiError(eresp)
 	}
 	return nil, nil
 }
@@ -265,47 +282,85 @@ func (c *ApiConnection) hasLoggedIn() bool {
 	return c.apikey != ""
 }
 
-func (c *ApiConnection) retry(ctxt context.Context, method, url string, ro *greq.RequestOptions, rs interface{}, sensitive, allowLogin bool) (*ApiErrorResponse, error) {
-	t1 := time.Now().Unix()
-	backoff := 1
-	var apiresp *ApiErrorResponse
-	for time.Now().Unix()-t1 < RetryTimeout {
+// SetRetryPolicy sets the RetryPolicy used for every request made with this
+// ApiConnection.  It should be called before the connection is shared between
+// goroutines.  A nil policy restores DefaultRetryPolicy
+func (c *ApiConnection) SetRetryPolicy(p RetryPolicy) {
+	c.retryPolicy = p
+}
+
+// retryPolicyFor returns the RetryPolicy for a request, preferring one set on
+// the context over the one configured on the connection
+func (c *ApiConnection) retryPolicyFor(ctxt context.Context) RetryPolicy {
+	if p, ok := retryPolicyFrom(ctxt); ok {
+		return p
+	}
+	if c.retryPolicy != nil {
+		return c.retryPolicy
+	}
+	return DefaultRetryPolicy
+}
+
+// retry re-issues a request that already failed once with apiresp until it succeeds, the
+// RetryPolicy gives up or the policy's timeout is reached
+func (c *ApiConnection) retry(ctxt context.Context, policy RetryPolicy, apiresp *ApiErrorResponse, method, url string, ro *greq.RequestOptions, rs interface{}, sensitive, allowLogin bool) (*ApiErrorResponse, error) {
+	t1 := time.Now()
+	timeout := policy.Timeout()
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		var retryAfter time.Duration
+		if apiresp != nil {
+			retryAfter = apiresp.RetryAfter
+		}
+		wait := policy.Backoff(attempt, retryAfter)
+		if time.Since(t1)+wait >= timeout {
+			// callers still get to see what the cluster answered last
+			return apiresp, &RetryTimeoutError{Last: AsError(apiresp, lastErr)}
+		}
+		if err := sleepCtx(ctxt, wait); err != nil {
+			return nil, err
+		}
+		// the failed attempt of a create may have created the object anyway
+		if adopted, err := c.adopt(ctxt, method, ro, rs); adopted || err != nil {
+			return nil, err
+		}
+
+		if c.metrics != nil {
+			c.metrics.IncRetries(method, c.canonicalRoute(url))
+		}
 		// any call to `do` from within a retry must use `false` for retry param
-		apiresp, err := c.do(ctxt, method, url, ro, rs, !canRetry, sensitive, allowLogin)
+		var err error
+		apiresp, err = c.do(withAttempt(ctxt, attempt+1), method, url, ro, rs, !canRetry, sensitive, allowLogin)
+		lastErr = err
 		if apiresp == nil && err == nil {
 			return nil, nil
 		}
-
-		// Retry on 503 and ConnectionErrors only
-		if apiresp != nil && apiresp.Http != 503 {
-			return apiresp, nil
-		} else if err != nil && !strings.Contains(err.Error(), "connect: connection refused") {
-			return nil, err
+		if apiresp != nil && apiresp.Http == Conflict {
+			// an earlier attempt got through after all
+			if adopted, err := c.adopt(ctxt, method, ro, rs); adopted || err != nil {
+				return nil, err
+			}
+		}
+		if !policy.ShouldRetry(attempt+1, apiresp, err) {
+			return apiresp, err
 		}
-
-		time.Sleep(time.Second * time.Duration(backoff*backoff))
-		backoff += 1
 	}
-	return apiresp, ErrRetryTimeout
 }
 
 func (c *ApiConnection) do(ctxt context.Context, method, url string, ro *greq.RequestOptions, rs interface{}, retry, sensitive, allowLogin bool) (*ApiErrorResponse, error) {
-	gurl := *c.baseUrl
+	base := c.endpoints.pick()
+	gurl := *base
 	gurl.Path = path.Join(gurl.Path, url)
+	route := canonicalizeRoute(gurl.Path, c.ApiVersion())
 	reqId := uuid.Must(uuid.NewRandom()).String()
-	sdata, err := json.Marshal(ro.JSON)
+	jdata, err := json.Marshal(ro.JSON)
 	if err != nil {
-		WithUserFields(ctxt, Log()).Errorf("Couldn't stringify data, %s", ro.JSON)
-	}
-	// Strip all CHAP credentails before printing to logs
-	if strings.Contains(string(sdata), "target_user_name") == true {
-		sdata = []byte("********")
-	}
-	if strings.Contains(string(sdata), "secret") == true {
-		sdata = []byte("********")
+		c.log(ctxt).Errorf("Couldn't stringify data, %s", ro.JSON)
 	}
+	// Strip all credentials before printing to logs
+	sdata := c.redactor.redact(jdata)
 	if sensitive {
-		sdata = []byte("********")
+		sdata = redactedValue
 	}
 	if ro.HTTPClient == nil && c.httpClient != nil {
 		ro.HTTPClient = c.httpClient
@@ -317,31 +372,43 @@ func (c *ApiConnection) do(ctxt context.Context, method, url string, ro *greq.Re
 		ro.Headers = make(map[string]string, 1)
 	}
 	ro.Headers["Datera-Driver"] = DateraDriver
-	tid, ok := ctxt.Value("tid").(string)
-	if !ok {
-		tid = "nil"
+	tid := TraceIDFrom(ctxt)
+	if isQuiet(ctxt) {
+		sdata = "<muted>"
+	}
+	// fail fast while the cluster is known to be down
+	if err := c.circuitAllow(route); err != nil {
+		c.log(ctxt).Debugf("Datera SDK request to %s rejected: %s", route, err)
+		return nil, err
+	}
+	// wait for our turn if the connection is rate limited
+	release, err := c.limiter.acquire(ctxt, route)
+	if err != nil {
+		c.circuitRecord(route, false, true)
+		return nil, err
 	}
-	if _, ok := ctxt.Value("quiet").(bool); ok {
-		sdata = []byte("<muted>")
+	span := c.startSpan(ctxt, route, ro.Headers)
+	if c.metrics != nil {
+		c.metrics.AddInFlight(1)
 	}
 	t1 := time.Now()
 	// This will be run before each request.  It's needed so we can get access
 	// to the headers/body passed with the request instead of just our custom ones
-	if Log().Logger.GetLevel() >= log.DebugLevel {
+	if c.log(ctxt).DebugEnabled() {
 		ro.BeforeRequest = func(h *http.Request) error {
-			sheaders, err := json.Marshal(h.Header)
+			sheaders, err := json.Marshal(redactHeaders(h.Header))
 			if err != nil {
-				WithUserFields(ctxt, Log()).Errorf("Couldn't stringify headers, %s", h.Header)
+				c.log(ctxt).Errorf("Couldn't stringify headers, %s", h.Header)
 			}
 
-			WithUserFields(ctxt, Log()).WithFields(log.Fields{
+			c.log(ctxt).WithFields(LogFields{
 				logTraceID:        tid,
 				"request_id":      reqId,
 				"request_method":  method,
 				"request_url":     gurl.String(),
-				"request_route":   canonicalizeRoute(gurl.Path, c.apiVersion),
+				"request_route":   route,
 				"request_headers": sheaders,
-				"request_payload": string(sdata),
+				"request_payload": sdata,
 				"query_params":    ro.Params,
 			}).Debugf("Datera SDK making request")
 			return nil
@@ -350,40 +417,109 @@ func (c *ApiConnection) do(ctxt context.Context, method, url string, ro *greq.Re
 
 	// The actual request happens here
 	// Context is passed through ro.Context
-	resp, err := greq.DoRegularRequest(method, gurl.String(), ro)
+	resp, rerr := greq.DoRegularRequest(method, gurl.String(), ro)
 
 	t2 := time.Now()
 	tDelta := t2.Sub(t1)
 	rdata := resp.String()
-	if _, ok := ctxt.Value("quiet").(bool); ok {
+	release()
+	// successful responses are only logged at debug level, don't bother
+	// redacting them otherwise
+	if sensitive {
+		rdata = redactedValue
+	} else if rerr != nil || !resp.Ok || c.log(ctxt).DebugEnabled() {
+		rdata = c.redactor.redact([]byte(rdata))
+	} else {
+		rdata = "<not logged>"
+	}
+	if c.metrics != nil {
+		c.metrics.AddInFlight(-1)
+		code := 0
+		if rerr == nil {
+			code = resp.StatusCode
+		}
+		c.metrics.ObserveRequest(method, route, code, tDelta)
+	}
+	if isQuiet(ctxt) {
 		rdata = "<muted>"
 	}
-	detailLog := WithUserFields(ctxt, Log()).WithFields(log.Fields{
+	detailLog := c.log(ctxt).WithFields(LogFields{
 		logTraceID:           tid,
 		"request_id":         reqId,
 		"response_timedelta": tDelta.Seconds(),
 		"request_method":     method,
 		"request_url":        gurl.String(),
-		"request_payload":    string(sdata),
-		"request_route":      canonicalizeRoute(gurl.Path, c.apiVersion),
+		"request_payload":    sdata,
+		"request_route":      route,
 		"response_payload":   rdata,
 		"response_code":      resp.StatusCode,
 	})
 
 	detailLog.Debugf("Datera SDK response received")
 
-	eresp, err := translateErrors(ctxt, resp, err)
+	eresp, err := c.translateErrors(ctxt, resp, rerr)
+	if check := adoptCheckFrom(ctxt); check != nil && method == "POST" {
+		check.attempted(resp, rerr, err)
+	}
+
+	if span != nil {
+		span.SetAttribute(SpanAttrMethod, method)
+		span.SetAttribute(SpanAttrURL, gurl.String())
+		span.SetAttribute(SpanAttrRoute, route)
+		span.SetAttribute(SpanAttrEndpoint, base.Host)
+		span.SetAttribute(SpanAttrRequestId, reqId)
+		span.SetAttribute(SpanAttrTraceId, tid)
+		span.SetAttribute(SpanAttrRetryAttempt, attemptFrom(ctxt))
+		if rerr == nil {
+			span.SetAttribute(SpanAttrStatusCode, resp.StatusCode)
+		}
+		// only API errors carry an api_req_id, see SpanAttrApiReqId
+		if eresp != nil && eresp.Id != 0 {
+			span.SetAttribute(SpanAttrApiReqId, eresp.Id)
+		}
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}
+
+	if rerr != nil && ctxt.Err() != nil {
+		// the request was aborted because the caller gave up on it, don't
+		// retry or try to make sense of the response
+		detailLog.Debugf("Datera SDK request cancelled: %s", ctxt.Err())
+		c.circuitRecord(route, false, true)
+		return nil, ctxt.Err()
+	}
+	if rerr != nil {
+		// the endpoint couldn't be reached, skip it for a while and try the
+		// next one right away if there is one
+		if c.endpoints.markDown(base) {
+			detailLog.Warnf("Datera SDK failing over from endpoint %s: %s", base.Host, rerr)
+			c.circuitRecord(route, false, true)
+			if adopted, err := c.adopt(ctxt, method, ro, rs); adopted || err != nil {
+				return nil, err
+			}
+			return c.do(ctxt, method, url, ro, rs, retry, sensitive, allowLogin)
+		}
+	} else {
+		c.endpoints.markUp(base)
+	}
+	c.circuitRecord(route, rerr != nil || resp.StatusCode == Retry503, false)
 
-	if err == badStatus[PermissionDenied] {
+	if eresp != nil && eresp.Http == PermissionDenied {
 		// if we have logged in successfully before we may just need to refresh the apikey
 		// and retry the original request
-		// However, because Login holds the mutex then if we got here as the result of a 401 during
+		// However, because everyone else waits on a login in progress then if we got here as the result of a 401 during
 		// a Login we can't do anything without deadlocking.  In this case we need to just return
 		// the error
 
 		if allowLogin && c.hasLoggedIn() {
-			c.Logout()
-			if apiresp, err2 := c.Login(ctxt); apiresp != nil || err2 != nil {
+			if c.metrics != nil {
+				c.metrics.IncReauthentications()
+			}
+			// every request that was sent with the expired apikey ends up
+			// here, only the first one logs in again
+			if apiresp, err2 := c.refreshSession(ctxt, ro.Headers["Auth-Token"], false); apiresp != nil || err2 != nil {
 				detailLog.Errorf("failed to re-authenticate before retrying request: %s", err2)
 				return apiresp, err2
 			}
@@ -398,8 +534,10 @@ func (c *ApiConnection) do(ctxt context.Context, method, url string, ro *greq.Re
 		return eresp, nil
 
 	}
-	if retry && (err == badStatus[Retry503] || err == badStatus[ConnectionError]) {
-		return c.retry(ctxt, method, url, ro, rs, sensitive, allowLogin)
+	if retry {
+		if policy := c.retryPolicyFor(ctxt); policy.ShouldRetry(1, eresp, err) {
+			return c.retry(ctxt, policy, eresp, method, url, ro, rs, sensitive, allowLogin)
+		}
 	}
 	if eresp != nil {
 		detailLog.Errorf("Received API Error %s", Pretty(eresp))
@@ -418,20 +556,32 @@ func (c *ApiConnection) do(ctxt context.Context, method, url string, ro *greq.Re
 }
 
 func (c *ApiConnection) doWithAuth(ctxt context.Context, method, url string, ro *greq.RequestOptions, rs interface{}) (*ApiErrorResponse, error) {
+	// endpoint calls get a nil connection from GetConn when their context
+	// doesn't have one
+	if c == nil {
+		return nil, ErrNoConnection
+	}
 	if ro == nil {
 		ro = &greq.RequestOptions{}
 	}
-	// don't need to check the loggingIn flag first because doWithAuth is not called from Login
-	// so that won't deadlock
-	if !c.hasLoggedIn() {
-		if apierr, err := c.Login(ctxt); apierr != nil || err != nil {
-			WithUserFields(ctxt, Log()).Errorf("Login failure: %s, %s", Pretty(apierr), err)
+	// doWithAuth is not called from Login so this won't deadlock
+	c.m.RLock()
+	apikey, expires := c.apikey, c.sessionExpires
+	c.m.RUnlock()
+	if apikey == "" || (!expires.IsZero() && time.Now().After(expires)) {
+		if apierr, err := c.refreshSession(ctxt, apikey, false); apierr != nil || err != nil {
+			c.log(ctxt).Errorf("Login failure: %s, %s", Pretty(apierr), err)
 			return apierr, err
 		}
 	}
 	c.m.RLock()
-	ro.Headers = map[string]string{"tenant": c.tenant, "Auth-Token": c.apikey}
+	tenant := c.tenant
+	if t, ok := tenantFrom(ctxt); ok {
+		tenant = t
+	}
+	ro.Headers = map[string]string{"tenant": tenant, "Auth-Token": c.apikey}
 	c.m.RUnlock()
+	atomic.StoreInt64(&c.lastUsed, time.Now().UnixNano())
 	return c.do(ctxt, method, url, ro, rs, canRetry, !isSensitive, allowLogin)
 }
 
@@ -439,31 +589,97 @@ func NewApiConnection(c *udc.UDC, secure bool) *ApiConnection {
 	return NewApiConnectionWithHTTPClient(c, secure, nil)
 }
 
+// NewApiConnectionWithHTTPClient is NewApiConnectionWithHTTPClientE, logging
+// the error and returning nil if the management IP can't be turned into an
+// endpoint
 func NewApiConnectionWithHTTPClient(c *udc.UDC, secure bool, client *http.Client) *ApiConnection {
-	u, err := makeBaseUrl(c.MgmtIp, c.ApiVersion, secure)
+	conn, err := NewApiConnectionWithHTTPClientE(c, secure, client)
 	if err != nil {
-		Log().Fatalf("%s", err)
+		defaultLog().Errorf("%s", err)
+		return nil
 	}
-	return &ApiConnection{
-		username:   c.Username,
-		password:   c.Password,
+	return conn
+}
+
+// NewApiConnectionWithHTTPClientE returns an ApiConnection sending its
+// requests with client, or a client using http.DefaultTransport when nil
+func NewApiConnectionWithHTTPClientE(c *udc.UDC, secure bool, client *http.Client) (*ApiConnection, error) {
+	conn := &ApiConnection{
+		credentials: StaticCredentialProvider{
+			Username: c.Username,
+			Password: c.Password,
+			Ldap:     c.Ldap,
+		},
 		apiVersion: c.ApiVersion,
 		tenant:     c.Tenant,
-		ldap:       c.Ldap,
 		secure:     secure,
-		baseUrl:    u,
-		httpClient: client,
+		mgmtIp:     c.MgmtIp,
 		m:          &sync.RWMutex{},
+		limiter:    newRequestLimiter(),
+		redactor:   newRedactor(),
+		flight:     newFlightGroup(),
 	}
+	conn.httpClient = conn.wrapClient(client)
+	if err := conn.SetEndpoints(EndpointConfig{}); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// NewApiConnectionWithTLS returns an ApiConnection whose HTTP client verifies
+// the cluster's certificate according to t
+func NewApiConnectionWithTLS(c *udc.UDC, secure bool, t *TLSConfig) (*ApiConnection, error) {
+	client, err := newTLSClient(t)
+	if err != nil {
+		return nil, err
+	}
+	return NewApiConnectionWithHTTPClientE(c, secure, client)
 }
 
 func (c *ApiConnection) Get(ctxt context.Context, url string, ro *greq.RequestOptions) (*ApiOuter, *ApiErrorResponse, error) {
-	rs := &ApiOuter{}
-	apiresp, err := c.doWithAuth(ctxt, "GET", url, ro, rs)
+	key := c.cacheKey(ctxt, url, ro)
+	if cached, ok := c.cacheGet(ctxt, key); ok {
+		return cached.(*ApiOuter), nil, nil
+	}
+	v, apiresp, err := c.coalesce(ctxt, url, ro, func() (interface{}, *ApiErrorResponse, error) {
+		start := c.cacheBegin(key)
+		defer c.cacheEnd(key, start)
+		rs := &ApiOuter{}
+		apiresp, err := c.doWithAuth(ctxt, "GET", url, ro, rs)
+		if apiresp == nil && err == nil {
+			c.cachePut(key, url, rs, start)
+		}
+		return rs, apiresp, err
+	})
+	rs, ok := v.(*ApiOuter)
+	if !ok {
+		rs = &ApiOuter{}
+	}
 	return rs, apiresp, err
 }
 
 func (c *ApiConnection) GetList(ctxt context.Context, url string, ro *greq.RequestOptions) (*ApiListOuter, *ApiErrorResponse, error) {
+	key := c.cacheKey(ctxt, url, ro)
+	if cached, ok := c.cacheGet(ctxt, key); ok {
+		return cached.(*ApiListOuter), nil, nil
+	}
+	v, apiresp, err := c.coalesce(ctxt, url, ro, func() (interface{}, *ApiErrorResponse, error) {
+		start := c.cacheBegin(key)
+		defer c.cacheEnd(key, start)
+		rs, apiresp, err := c.getList(ctxt, url, ro)
+		if apiresp == nil && err == nil {
+			c.cachePut(key, url, rs, start)
+		}
+		return rs, apiresp, err
+	})
+	rs, ok := v.(*ApiListOuter)
+	if !ok {
+		rs = &ApiListOuter{}
+	}
+	return rs, apiresp, err
+}
+
+func (c *ApiConnection) getList(ctxt context.Context, url string, ro *greq.RequestOptions) (*ApiListOuter, *ApiErrorResponse, error) {
 	rs := &ApiListOuter{}
 	apiresp, err := c.doWithAuth(ctxt, "GET", url, ro, rs)
 	// TODO:(_alastor_) handle pulling paged entries
@@ -473,6 +689,15 @@ func (c *ApiConnection) GetList(ctxt context.Context, url string, ro *greq.Reque
 		if lp.Limit != 0 || lp.Offset != 0 {
 			return rs, apiresp, err
 		}
+		if workers := c.pageWorkersFor(ctxt); workers > 1 && len(rs.Data) > 0 {
+			tcnt := rs.TotalCount()
+			data, apiresp, err := c.getPages(ctxt, url, ro, len(rs.Data), len(rs.Data), tcnt, workers)
+			rs.Data = append(rs.Data, data...)
+			if apiresp == nil && err == nil && len(rs.Data) != tcnt {
+				c.log(ctxt).Warnf("Retrieved %d of %d entries from %s", len(rs.Data), tcnt, url)
+			}
+			return rs, apiresp, err
+		}
 		data := rs.Data
 		offset := 0
 		tcnt := 0
@@ -508,70 +733,61 @@ func (c *ApiConnection) GetList(ctxt context.Context, url string, ro *greq.Reque
 func (c *ApiConnection) Put(ctxt context.Context, url string, ro *greq.RequestOptions) (*ApiOuter, *ApiErrorResponse, error) {
 	rs := &ApiOuter{}
 	apiresp, err := c.doWithAuth(ctxt, "PUT", url, ro, rs)
+	c.invalidate(url)
 	return rs, apiresp, err
 }
 
 func (c *ApiConnection) Post(ctxt context.Context, url string, ro *greq.RequestOptions) (*ApiOuter, *ApiErrorResponse, error) {
 	rs := &ApiOuter{}
 	apiresp, err := c.doWithAuth(ctxt, "POST", url, ro, rs)
+	c.invalidate(url)
 	return rs, apiresp, err
 }
 
 func (c *ApiConnection) Delete(ctxt context.Context, url string, ro *greq.RequestOptions) (*ApiOuter, *ApiErrorResponse, error) {
 	rs := &ApiOuter{}
 	apiresp, err := c.doWithAuth(ctxt, "DELETE", url, ro, rs)
+	c.invalidate(url)
 	return rs, apiresp, err
 }
 
+// ApiVersions returns the API versions supported by the cluster as reported by
+// it, or an empty list if they couldn't be retrieved.  See FetchApiVersions
 func (c *ApiConnection) ApiVersions() []string {
-	gurl := *c.baseUrl
-	gurl.Path = "api_versions"
-	resp, err := greq.DoRegularRequest("GET", gurl.String(), nil)
+	apiv, err := c.apiVersions(context.Background())
 	if err != nil {
+		c.log(context.Background()).Errorf("Failed retrieving API versions: %s", err)
 		return []string{}
 	}
-	apiv := &ApiVersions{}
-	resp.JSON(apiv)
 	return apiv.ApiVersions
 }
 
+// Login authenticates the connection unless it already has a session.  Any
+// number of concurrent calls result in a single login request
 func (c *ApiConnection) Login(ctxt context.Context) (*ApiErrorResponse, error) {
-	c.m.Lock()
-	defer c.m.Unlock()
-
-	// can't call hasLoggedIn since that needs to RLock but this is equivalent
-	if c.apikey != "" {
-		// any time the connection has an apikey we can skip the login because
-		// the apikey gets cleared after a session expiration before attempting to login
-		// therefore a non-empty apikey can be assumed to be valid
-
-		return nil, nil
-	}
-
-	login := &ApiLogin{}
-	ro := &greq.RequestOptions{
-		Data: map[string]string{
-			"name":     c.username,
-			"password": c.password,
-		},
-	}
-	if c.ldap != "" {
-		ro.Data["remote_server"] = c.ldap
-	}
-
-	apiresp, err := c.do(ctxt, "PUT", "login", ro, login, canRetry, isSensitive, !allowLogin)
-
-	if (apiresp != nil && apiresp.Http == PermissionDenied) || (err != nil && err == badStatus[PermissionDenied]) {
-		c.apikey = ""
-	} else {
-		c.apikey = login.Key
-	}
-
-	return apiresp, err
+	return c.refreshSession(ctxt, "", false)
 }
 
+// Logout forgets the session of the connection without ending it on the
+// cluster, see LogoutSession
 func (c *ApiConnection) Logout() {
 	c.m.Lock()
-	defer c.m.Unlock()
+	apikey, key := c.apikey, c.tokenKey
 	c.apikey = ""
+	c.sessionExpires = time.Time{}
+	if c.refreshTimer != nil {
+		c.refreshTimer.Stop()
+		c.refreshTimer = nil
+	}
+	c.m.Unlock()
+	if c.tokenStore == nil || apikey == "" {
+		return
+	}
+	// don't let anyone else reuse the session unless it was already replaced
+	ctxt := context.Background()
+	if token, err := c.tokenStore.Load(ctxt, key); err == nil && token == apikey {
+		if err = c.tokenStore.Delete(ctxt, key); err != nil {
+			c.log(ctxt).Warnf("Failed deleting stored apikey: %s", err)
+		}
+	}
 }
@@ -0,0 +1,76 @@
+package dsdk
+
+import (
+	"context"
+)
+
+// ctxKey is unexported so no other package can set or read these values
+// without going through the helpers below
+type ctxKey int
+
+const (
+	connCtxKey ctxKey = iota
+	traceIDCtxKey
+	quietCtxKey
+	tenantCtxKey
+	retryPolicyCtxKey
+	attemptCtxKey
+	pageWorkersCtxKey
+	noCacheCtxKey
+	adoptCheckCtxKey
+)
+
+// WithConnection returns a context that makes endpoint calls use conn
+func WithConnection(ctxt context.Context, conn *ApiConnection) context.Context {
+	return context.WithValue(ctxt, connCtxKey, conn)
+}
+
+// ConnectionFrom returns the connection set with WithConnection, or
+// ErrNoConnection if there is none
+func ConnectionFrom(ctxt context.Context) (*ApiConnection, error) {
+	if ctxt == nil {
+		return nil, ErrNoConnection
+	}
+	conn, ok := ctxt.Value(connCtxKey).(*ApiConnection)
+	if !ok || conn == nil {
+		return nil, ErrNoConnection
+	}
+	return conn, nil
+}
+
+// WithTraceID returns a context whose requests are logged with tid, eg. a
+// uuid for the current transaction
+func WithTraceID(ctxt context.Context, tid string) context.Context {
+	return context.WithValue(ctxt, traceIDCtxKey, tid)
+}
+
+// TraceIDFrom returns the trace id set with WithTraceID, or "nil" if there is
+// none
+func TraceIDFrom(ctxt context.Context) string {
+	if tid, ok := ctxt.Value(traceIDCtxKey).(string); ok {
+		return tid
+	}
+	return "nil"
+}
+
+// WithQuiet returns a context whose requests are logged without their
+// payloads
+func WithQuiet(ctxt context.Context) context.Context {
+	return context.WithValue(ctxt, quietCtxKey, true)
+}
+
+func isQuiet(ctxt context.Context) bool {
+	q, _ := ctxt.Value(quietCtxKey).(bool)
+	return q
+}
+
+// WithTenant returns a context that makes requests made with it use tenant,
+// eg. "/root/foo", instead of the tenant configured on the ApiConnection
+func WithTenant(ctxt context.Context, tenant string) context.Context {
+	return context.WithValue(ctxt, tenantCtxKey, tenant)
+}
+
+func tenantFrom(ctxt context.Context) (string, bool) {
+	t, ok := ctxt.Value(tenantCtxKey).(string)
+	return t, ok
+}
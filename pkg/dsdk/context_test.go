@@ -0,0 +1,44 @@
+package dsdk
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestContextHelpers(t *testing.T) {
+	conn := &ApiConnection{}
+	ctxt := WithQuiet(WithTraceID(WithConnection(context.Background(), conn), "my-tid"))
+	if c, err := ConnectionFrom(ctxt); c != conn || err != nil {
+		t.Errorf("expected the connection, got %v, %v", c, err)
+	}
+	if tid := TraceIDFrom(ctxt); tid != "my-tid" {
+		t.Errorf("expected my-tid, got %s", tid)
+	}
+	if !isQuiet(ctxt) {
+		t.Error("expected a quiet context")
+	}
+
+	// raw string keys are ignored
+	ctxt = context.WithValue(context.Background(), "conn", conn)
+	if _, err := ConnectionFrom(ctxt); err != ErrNoConnection {
+		t.Errorf("expected ErrNoConnection, got %v", err)
+	}
+	if tid := TraceIDFrom(ctxt); tid != "nil" {
+		t.Errorf("expected no trace id, got %s", tid)
+	}
+}
+
+func TestNoConnection(t *testing.T) {
+	sys := newSystem("/")
+	if _, _, err := sys.Get(&SystemGetRequest{Ctxt: context.Background()}); !errors.Is(err, ErrNoConnection) {
+		t.Errorf("expected ErrNoConnection, got %v", err)
+	}
+	if _, _, err := sys.Get(&SystemGetRequest{}); !errors.Is(err, ErrNoConnection) {
+		t.Errorf("expected ErrNoConnection without a context, got %v", err)
+	}
+	ais := newAppInstances("/")
+	if _, err := ais.ListE(&AppInstancesListRequest{Ctxt: context.Background()}); !errors.Is(err, ErrNoConnection) {
+		t.Errorf("expected ErrNoConnection, got %v", err)
+	}
+}
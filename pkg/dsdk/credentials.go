@@ -0,0 +1,216 @@
+package dsdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	udc "github.com/Datera/go-udc/pkg/udc"
+)
+
+// ErrNoCredentials is returned by a CredentialProvider that has no username
+var ErrNoCredentials = errors.New("no credentials available")
+
+// Credentials are used to log in to the Datera API.  The JSON form is the same
+// as the one used by UDC config files
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Ldap is the remote LDAP server to authenticate against, if any
+	Ldap string `json:"ldap"`
+}
+
+// CredentialProvider is asked for credentials every time the connection logs
+// in, so providers that read them from an external source pick up rotated
+// secrets without the SDK being recreated
+type CredentialProvider interface {
+	Credentials(ctxt context.Context) (*Credentials, error)
+}
+
+// StaticCredentialProvider always returns the same credentials
+type StaticCredentialProvider Credentials
+
+func (p StaticCredentialProvider) Credentials(ctxt context.Context) (*Credentials, error) {
+	c := Credentials(p)
+	return &c, nil
+}
+
+// EnvCredentialProvider reads credentials from environment variables.  Empty
+// variable names default to the ones used by UDC, DAT_USER, DAT_PASS and
+// DAT_LDAP
+type EnvCredentialProvider struct {
+	UsernameVar string
+	PasswordVar string
+	LdapVar     string
+}
+
+func (p EnvCredentialProvider) Credentials(ctxt context.Context) (*Credentials, error) {
+	get := func(name, def string) string {
+		if name == "" {
+			name = def
+		}
+		return os.Getenv(name)
+	}
+	c := &Credentials{
+		Username: get(p.UsernameVar, udc.EnvUser),
+		Password: get(p.PasswordVar, udc.EnvPass),
+		Ldap:     get(p.LdapVar, udc.EnvLdap),
+	}
+	if c.Username == "" {
+		return nil, fmt.Errorf("%w in environment", ErrNoCredentials)
+	}
+	return c, nil
+}
+
+// FileCredentialProvider reads credentials from a JSON file, eg. a UDC config
+// file or a mounted Kubernetes secret.  The file is read on every login
+type FileCredentialProvider struct {
+	Path string
+}
+
+func (p FileCredentialProvider) Credentials(ctxt context.Context) (*Credentials, error) {
+	b, err := ioutil.ReadFile(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Credentials{}
+	if err = json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("failed parsing credentials file %s: %w", p.Path, err)
+	}
+	if c.Username == "" {
+		return nil, fmt.Errorf("%w in %s", ErrNoCredentials, p.Path)
+	}
+	return c, nil
+}
+
+// ExecCredentialProvider runs a command that prints the credentials as JSON
+// to its stdout, eg. a wrapper around a secrets manager CLI
+type ExecCredentialProvider struct {
+	Command string
+	Args    []string
+	// Env is added to the environment of the command
+	Env []string
+}
+
+func (p ExecCredentialProvider) Credentials(ctxt context.Context) (*Credentials, error) {
+	cmd := exec.CommandContext(ctxt, p.Command, p.Args...)
+	cmd.Env = append(os.Environ(), p.Env...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("credential command %s failed: %w: %s", p.Command, err, strings.TrimSpace(stderr.String()))
+	}
+	c := &Credentials{}
+	if err = json.Unmarshal(out, c); err != nil {
+		return nil, fmt.Errorf("failed parsing output of credential command %s: %w", p.Command, err)
+	}
+	if c.Username == "" {
+		return nil, fmt.Errorf("%w from %s", ErrNoCredentials, p.Command)
+	}
+	return c, nil
+}
+
+// SetCredentialProvider replaces the credentials from the UDC.  It should be
+// called before the connection is shared between goroutines
+func (c *ApiConnection) SetCredentialProvider(p CredentialProvider) {
+	c.credentials = p
+}
+
+// TokenStore persists the API key of a session so it can be reused by other
+// connections and processes instead of each of them logging in
+type TokenStore interface {
+	// Load returns the stored token for key or "" if there is none
+	Load(ctxt context.Context, key string) (string, error)
+	Store(ctxt context.Context, key, token string) error
+	Delete(ctxt context.Context, key string) error
+}
+
+// FileTokenStore keeps tokens in files only readable by the current user
+type FileTokenStore struct {
+	// Dir defaults to a datera directory in the user's cache directory
+	Dir string
+}
+
+func (s FileTokenStore) path(key string) (string, error) {
+	dir := s.Dir
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(cache, "datera")
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".token"), nil
+}
+
+func (s FileTokenStore) Load(ctxt context.Context, key string) (string, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	b, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(b)), err
+}
+
+func (s FileTokenStore) Store(ctxt context.Context, key, token string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	// write to a temporary file first so readers never see a partial token
+	f, err := ioutil.TempFile(filepath.Dir(p), ".token")
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(token)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), p)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func (s FileTokenStore) Delete(ctxt context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(p); os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// SetTokenStore makes the connection reuse API keys found in s and save the
+// ones it gets from logging in.  It should be called before the connection is
+// shared between goroutines
+func (c *ApiConnection) SetTokenStore(s TokenStore) {
+	c.tokenStore = s
+}
+
+// sessionKey identifies the session of a user on the cluster in the TokenStore
+func (c *ApiConnection) sessionKey(creds *Credentials) string {
+	return fmt.Sprintf("%s|%s|%s", c.mgmtIp, creds.Ldap, creds.Username)
+}
@@ -0,0 +1,81 @@
+package dsdk
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCredentialProviders(t *testing.T) {
+	ctxt := context.Background()
+	want := Credentials{Username: "admin", Password: "pass", Ldap: "ldap1"}
+
+	os.Setenv("TEST_DAT_USER", "admin")
+	os.Setenv("TEST_DAT_PASS", "pass")
+	os.Setenv("TEST_DAT_LDAP", "ldap1")
+	defer os.Unsetenv("TEST_DAT_USER")
+	defer os.Unsetenv("TEST_DAT_PASS")
+	defer os.Unsetenv("TEST_DAT_LDAP")
+	c, err := EnvCredentialProvider{"TEST_DAT_USER", "TEST_DAT_PASS", "TEST_DAT_LDAP"}.Credentials(ctxt)
+	if err != nil || *c != want {
+		t.Errorf("env: got %v, %v", c, err)
+	}
+	if _, err = (EnvCredentialProvider{UsernameVar: "TEST_DAT_NOPE"}).Credentials(ctxt); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("env: expected ErrNoCredentials, got %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "dsdk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f := filepath.Join(dir, "creds.json")
+	if err = ioutil.WriteFile(f, []byte(`{"username": "admin", "password": "pass", "ldap": "ldap1", "mgmt_ip": "1.1.1.1"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c, err = FileCredentialProvider{Path: f}.Credentials(ctxt)
+	if err != nil || *c != want {
+		t.Errorf("file: got %v, %v", c, err)
+	}
+
+	c, err = ExecCredentialProvider{
+		Command: "sh",
+		Args:    []string{"-c", `echo "{\"username\": \"$U\", \"password\": \"pass\", \"ldap\": \"ldap1\"}"`},
+		Env:     []string{"U=admin"},
+	}.Credentials(ctxt)
+	if err != nil || *c != want {
+		t.Errorf("exec: got %v, %v", c, err)
+	}
+	if _, err = (ExecCredentialProvider{Command: "false"}).Credentials(ctxt); err == nil {
+		t.Error("exec: expected a failing command to return an error")
+	}
+}
+
+func TestFileTokenStore(t *testing.T) {
+	ctxt := context.Background()
+	dir, err := ioutil.TempDir("", "dsdk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := FileTokenStore{Dir: filepath.Join(dir, "tokens")}
+
+	if tok, err := s.Load(ctxt, "key"); tok != "" || err != nil {
+		t.Errorf("expected no token, got %q, %v", tok, err)
+	}
+	if err = s.Store(ctxt, "key", "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if tok, err := s.Load(ctxt, "key"); tok != "abc" || err != nil {
+		t.Errorf("expected abc, got %q, %v", tok, err)
+	}
+	if err = s.Delete(ctxt, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Delete(ctxt, "key"); err != nil {
+		t.Errorf("deleting a missing token should succeed, got %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package dsdk
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	greq "github.com/levigross/grequests"
+)
+
+const (
+	DefaultHTTPPort         = 7717
+	DefaultHTTPSPort        = 7718
+	DefaultEndpointCooldown = 30 * time.Second
+)
+
+// discoveryRetryInitial and discoveryRetryMax bound the wait between two
+// attempts of a failed endpoint discovery
+var (
+	discoveryRetryInitial = time.Second
+	discoveryRetryMax     = 5 * time.Minute
+)
+
+// EndpointConfig describes the management endpoints an ApiConnection may send
+// requests to.  Requests always go to the first healthy endpoint in Hosts, an
+// endpoint that fails to connect is skipped until its Cooldown expires.
+type EndpointConfig struct {
+	// Hosts are management IPs, VIPs or host names in order of preference.
+	// A host may include a port which overrides HTTPPort/HTTPSPort
+	Hosts []string
+	// HTTPPort and HTTPSPort default to 7717 and 7718
+	HTTPPort  int
+	HTTPSPort int
+	// Cooldown is how long an endpoint that failed is skipped for.  Defaults to
+	// DefaultEndpointCooldown
+	Cooldown time.Duration
+	// Discover adds the cluster's management VIP and the management IPs of
+	// every storage node to the endpoints after the first successful login.
+	// Discovery is retried in the background until it succeeds
+	Discover bool
+}
+
+type endpointPool struct {
+	m         sync.Mutex
+	urls      []*url.URL
+	downUntil []time.Time
+	cooldown  time.Duration
+}
+
+func newEndpointPool(cooldown time.Duration, urls ...*url.URL) *endpointPool {
+	if cooldown <= 0 {
+		cooldown = DefaultEndpointCooldown
+	}
+	p := &endpointPool{cooldown: cooldown}
+	p.add(urls...)
+	return p
+}
+
+// add appends endpoints that aren't already part of the pool
+func (p *endpointPool) add(urls ...*url.URL) int {
+	p.m.Lock()
+	defer p.m.Unlock()
+	added := 0
+	for _, u := range urls {
+		dup := false
+		for _, e := range p.urls {
+			if e.Host == u.Host {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			p.urls = append(p.urls, u)
+			p.downUntil = append(p.downUntil, time.Time{})
+			added++
+		}
+	}
+	return added
+}
+
+// pick returns the first healthy endpoint or, if all of them are down, the one
+// that will come out of its cooldown first
+func (p *endpointPool) pick() *url.URL {
+	p.m.Lock()
+	defer p.m.Unlock()
+	now := time.Now()
+	best := 0
+	for i, until := range p.downUntil {
+		if now.After(until) {
+			return p.urls[i]
+		}
+		if until.Before(p.downUntil[best]) {
+			best = i
+		}
+	}
+	return p.urls[best]
+}
+
+// markDown puts an endpoint into its cooldown and reports whether another
+// healthy endpoint is left to fail over to
+func (p *endpointPool) markDown(u *url.URL) bool {
+	p.m.Lock()
+	defer p.m.Unlock()
+	now := time.Now()
+	healthy := false
+	for i, e := range p.urls {
+		if e.Host == u.Host {
+			p.downUntil[i] = now.Add(p.cooldown)
+		} else if now.After(p.downUntil[i]) {
+			healthy = true
+		}
+	}
+	return healthy
+}
+
+func (p *endpointPool) markUp(u *url.URL) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	for i, e := range p.urls {
+		if e.Host == u.Host {
+			p.downUntil[i] = time.Time{}
+			return
+		}
+	}
+}
+
+// setApiVersion points every endpoint at API version v.  The urls are replaced
+// rather than changed since requests in flight hold on to them
+func (p *endpointPool) setApiVersion(v string) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	for i, u := range p.urls {
+		nu := *u
+		nu.Path = "/v" + v
+		p.urls[i] = &nu
+	}
+}
+
+func (p *endpointPool) hosts() []string {
+	p.m.Lock()
+	defer p.m.Unlock()
+	r := make([]string, len(p.urls))
+	for i, u := range p.urls {
+		r[i] = u.Host
+	}
+	return r
+}
+
+func (c *ApiConnection) makeEndpointUrls(apiVersion string, hosts ...string) ([]*url.URL, error) {
+	port := c.endpointConf.HTTPPort
+	if port == 0 {
+		port = DefaultHTTPPort
+	}
+	if c.secure {
+		port = c.endpointConf.HTTPSPort
+		if port == 0 {
+			port = DefaultHTTPSPort
+		}
+	}
+	urls := []*url.URL{}
+	for _, h := range hosts {
+		h = strings.Trim(strings.TrimSpace(h), "/")
+		if h == "" {
+			continue
+		}
+		u, err := makeBaseUrl(h, apiVersion, c.secure, port)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
+
+// SetEndpoints replaces the endpoints requests are sent to.  It should be
+// called before the connection is shared between goroutines.  When
+// conf.Hosts is empty the management IP from the UDC is used
+func (c *ApiConnection) SetEndpoints(conf EndpointConfig) error {
+	c.endpointConf = conf
+	hosts := conf.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{c.mgmtIp}
+	}
+	urls, err := c.makeEndpointUrls(c.ApiVersion(), hosts...)
+	if err != nil {
+		return err
+	}
+	if len(urls) == 0 {
+		return fmt.Errorf("no valid endpoints in %v", hosts)
+	}
+	c.endpoints = newEndpointPool(conf.Cooldown, urls...)
+	return nil
+}
+
+// Endpoints returns the host:port of every endpoint known to the connection
+func (c *ApiConnection) Endpoints() []string {
+	return c.endpoints.hosts()
+}
+
+// DiscoverEndpoints adds the management VIP of the cluster and the management
+// IPs of all storage nodes to the endpoints of the connection
+func (c *ApiConnection) DiscoverEndpoints(ctxt context.Context) error {
+	hosts := []string{}
+	rs, apierr, err := c.Get(ctxt, "system/network/mgmt_vip", &greq.RequestOptions{})
+	if err = AsError(apierr, err); err != nil {
+		return err
+	}
+	if paths, ok := rs.Data["network_paths"].([]interface{}); ok {
+		for _, np := range paths {
+			if m, ok := np.(map[string]interface{}); ok {
+				if ip, ok := m["ip"].(string); ok {
+					hosts = append(hosts, ip)
+				}
+			}
+		}
+	}
+	lrs, apierr, err := c.GetList(ctxt, "storage_nodes", &greq.RequestOptions{})
+	if err = AsError(apierr, err); err != nil {
+		return err
+	}
+	for _, data := range lrs.Data {
+		sn := &StorageNode{}
+		if err = FillStruct(data.(map[string]interface{}), sn); err != nil {
+			return err
+		}
+		hosts = append(hosts, sn.MgmtIp1, sn.MgmtIp2)
+	}
+	urls, err := c.makeEndpointUrls(c.ApiVersion(), hosts...)
+	if err != nil {
+		return err
+	}
+	if n := c.endpoints.add(urls...); n > 0 {
+		c.log(ctxt).Debugf("Discovered %d new endpoints, now using %v", n, c.Endpoints())
+	}
+	return nil
+}
+
+// discoverEndpointsOnce runs endpoint discovery in the background after the
+// first successful login if it was requested
+func (c *ApiConnection) discoverEndpointsOnce() {
+	if !c.endpointConf.Discover {
+		return
+	}
+	c.discoverOnce.Do(func() {
+		go c.discoverEndpointsUntilDone()
+	})
+}
+
+// discoverEndpointsUntilDone retries endpoint discovery with backoff until it
+// succeeds.  A cluster that is briefly unreachable at startup would otherwise
+// limit failover to the configured endpoints for the life of the connection
+func (c *ApiConnection) discoverEndpointsUntilDone() {
+	wait := discoveryRetryInitial
+	for {
+		ctxt, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := c.DiscoverEndpoints(ctxt)
+		cancel()
+		if err == nil {
+			return
+		}
+		c.log(ctxt).Errorf("Endpoint discovery failed, retrying in %s: %s", wait, err)
+		time.Sleep(wait)
+		wait *= 2
+		if wait > discoveryRetryMax {
+			wait = discoveryRetryMax
+		}
+	}
+}
+
+func hostPort(h string, port int) string {
+	if _, _, err := net.SplitHostPort(h); err == nil {
+		return h
+	}
+	// bare IPv6 addresses need brackets before a port can be appended
+	return net.JoinHostPort(strings.Trim(h, "[]"), strconv.Itoa(port))
+}
@@ -0,0 +1,61 @@
+package dsdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	udc "github.com/Datera/go-udc/pkg/udc"
+)
+
+func TestDiscoverEndpointsRetried(t *testing.T) {
+	defer func(d time.Duration) { discoveryRetryInitial = d }(discoveryRetryInitial)
+	discoveryRetryInitial = 10 * time.Millisecond
+
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2.2/login":
+			fmt.Fprint(w, `{"key": "thekey"}`)
+		case "/v2.2/system/network/mgmt_vip":
+			// the cluster isn't ready the first time around
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				w.WriteHeader(500)
+				fmt.Fprint(w, `{"http": 500, "message": "not ready"}`)
+				return
+			}
+			fmt.Fprint(w, `{"data": {"network_paths": [{"ip": "10.0.0.1"}]}}`)
+		case "/v2.2/storage_nodes":
+			fmt.Fprint(w, `{"data": []}`)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+	c, err := NewApiConnectionWithHTTPClientE(&udc.UDC{MgmtIp: host, Username: "foo", Password: "bar", ApiVersion: "2.2"}, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.SetEndpoints(EndpointConfig{Hosts: []string{host}, Discover: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Login(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(c.Endpoints()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected discovery to be retried, got endpoints %v after %d attempts", c.Endpoints(), atomic.LoadInt32(&attempts))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := c.Endpoints(); got[1] != "10.0.0.1:7717" {
+		t.Errorf("unexpected endpoints %v", got)
+	}
+}
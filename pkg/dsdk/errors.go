@@ -0,0 +1,139 @@
+package dsdk
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Every endpoint method returning (*T, *ApiErrorResponse, error) has a twin
+// with an "E" suffix returning (*T, error) instead.  API errors returned by
+// those are one of the typed errors below (or the *ApiErrorResponse itself for
+// statuses without a dedicated type) so they can be inspected with errors.Is
+// and errors.As:
+//
+//	ai, err := sdk.AppInstances.GetE(&dsdk.AppInstancesGetRequest{Ctxt: ctxt, Id: id})
+//	if errors.Is(err, dsdk.ErrNotFound) {
+//		...
+//	}
+//	var apierr *dsdk.ApiErrorResponse
+//	if errors.As(err, &apierr) {
+//		fmt.Println(apierr.Message)
+//	}
+
+var (
+	ErrNotFound         = errors.New("resource not found")
+	ErrConflict         = errors.New("resource conflict")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrValidation       = errors.New("invalid request")
+	ErrUnavailable      = errors.New("service unavailable")
+	// ErrConnection is returned when the API could not be reached at all.  It
+	// matches ErrUnavailable as well
+	ErrConnection = fmt.Errorf("ConnectionError: %w", ErrUnavailable)
+	// ErrNoConnection is returned by endpoint calls whose context wasn't
+	// created with SDK.NewContext, SDK.WithContext or WithConnection
+	ErrNoConnection = errors.New("no *ApiConnection in context, use sdk.NewContext() to obtain one")
+)
+
+// RetryTimeoutError is returned when the retries of a request took longer than
+// the RetryPolicy allows.  It matches ErrRetryTimeout and unwraps to the error
+// of the last attempt, eg. an *UnavailableError for a 503
+type RetryTimeoutError struct {
+	// Last is the error of the last attempt
+	Last error
+}
+
+func (e *RetryTimeoutError) Error() string {
+	if e.Last == nil {
+		return ErrRetryTimeout.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrRetryTimeout, e.Last)
+}
+
+func (e *RetryTimeoutError) Is(target error) bool { return target == ErrRetryTimeout }
+func (e *RetryTimeoutError) Unwrap() error        { return e.Last }
+
+func (e *ApiErrorResponse) Error() string {
+	msg := e.Message
+	if msg == "" && len(e.Errors) > 0 {
+		msg = e.Errors[0]
+	}
+	if e.Name != "" {
+		return fmt.Sprintf("datera api error %d %s: %s", e.Http, e.Name, msg)
+	}
+	return fmt.Sprintf("datera api error %d: %s", e.Http, msg)
+}
+
+// Is reports whether target is an *ApiErrorResponse whose non-zero Http, Code,
+// Name and Message fields all match this one, so that
+// errors.Is(err, &ApiErrorResponse{Http: 404}) works as expected
+func (e *ApiErrorResponse) Is(target error) bool {
+	t, ok := target.(*ApiErrorResponse)
+	if !ok || t == nil {
+		return false
+	}
+	if e == t {
+		return true
+	}
+	if t.Http == 0 && t.Code == 0 && t.Name == "" && t.Message == "" {
+		return false
+	}
+	return (t.Http == 0 || t.Http == e.Http) &&
+		(t.Code == 0 || t.Code == e.Code) &&
+		(t.Name == "" || t.Name == e.Name) &&
+		(t.Message == "" || t.Message == e.Message)
+}
+
+type NotFoundError struct{ *ApiErrorResponse }
+
+func (e *NotFoundError) Unwrap() error        { return e.ApiErrorResponse }
+func (e *NotFoundError) Is(target error) bool { return target == ErrNotFound }
+
+type ConflictError struct{ *ApiErrorResponse }
+
+func (e *ConflictError) Unwrap() error        { return e.ApiErrorResponse }
+func (e *ConflictError) Is(target error) bool { return target == ErrConflict }
+
+type PermissionDeniedError struct{ *ApiErrorResponse }
+
+func (e *PermissionDeniedError) Unwrap() error        { return e.ApiErrorResponse }
+func (e *PermissionDeniedError) Is(target error) bool { return target == ErrPermissionDenied }
+
+type ValidationError struct{ *ApiErrorResponse }
+
+func (e *ValidationError) Unwrap() error        { return e.ApiErrorResponse }
+func (e *ValidationError) Is(target error) bool { return target == ErrValidation }
+
+type UnavailableError struct{ *ApiErrorResponse }
+
+func (e *UnavailableError) Unwrap() error        { return e.ApiErrorResponse }
+func (e *UnavailableError) Is(target error) bool { return target == ErrUnavailable }
+
+// NewApiError wraps an ApiErrorResponse in the typed error matching its HTTP
+// status.  Statuses without a dedicated type are returned as is
+func NewApiError(apierr *ApiErrorResponse) error {
+	if apierr == nil {
+		return nil
+	}
+	switch apierr.Http {
+	case InvalidRequest, 422:
+		return &ValidationError{apierr}
+	case PermissionDenied, 403:
+		return &PermissionDeniedError{apierr}
+	case 404:
+		return &NotFoundError{apierr}
+	case Conflict:
+		return &ConflictError{apierr}
+	case Retry503:
+		return &UnavailableError{apierr}
+	}
+	return apierr
+}
+
+// AsError folds the (*ApiErrorResponse, error) pair returned by the SDK into a
+// single error
+func AsError(apierr *ApiErrorResponse, err error) error {
+	if err != nil {
+		return err
+	}
+	return NewApiError(apierr)
+}
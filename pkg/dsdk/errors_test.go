@@ -0,0 +1,62 @@
+package dsdk
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestNewApiError(t *testing.T) {
+	tests := []struct {
+		http int
+		want error
+	}{
+		{http: 400, want: ErrValidation},
+		{http: 401, want: ErrPermissionDenied},
+		{http: 403, want: ErrPermissionDenied},
+		{http: 404, want: ErrNotFound},
+		{http: 409, want: ErrConflict},
+		{http: 503, want: ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.http), func(t *testing.T) {
+			apierr := &ApiErrorResponse{Http: tt.http, Message: "boom"}
+			err := fmt.Errorf("wrapped: %w", NewApiError(apierr))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.want)
+			}
+			var got *ApiErrorResponse
+			if !errors.As(err, &got) || got != apierr {
+				t.Errorf("errors.As did not return the original ApiErrorResponse")
+			}
+			if !errors.Is(err, &ApiErrorResponse{Http: tt.http}) {
+				t.Errorf("errors.Is did not match an ApiErrorResponse template")
+			}
+		})
+	}
+
+	if err := NewApiError(&ApiErrorResponse{Http: 500}); errors.Is(err, ErrNotFound) || err == nil {
+		t.Errorf("unexpected error for a 500: %v", err)
+	}
+	if err := AsError(nil, nil); err != nil {
+		t.Errorf("AsError(nil, nil) = %v", err)
+	}
+	if !errors.Is(ErrConnection, ErrUnavailable) {
+		t.Errorf("ErrConnection should match ErrUnavailable")
+	}
+}
+
+func TestRetryTimeoutError(t *testing.T) {
+	apierr := &ApiErrorResponse{Http: Retry503, Message: "overloaded"}
+	var err error = &RetryTimeoutError{Last: NewApiError(apierr)}
+	if !errors.Is(err, ErrRetryTimeout) || !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected %v to match ErrRetryTimeout and ErrUnavailable", err)
+	}
+	var got *ApiErrorResponse
+	if !errors.As(err, &got) || got != apierr {
+		t.Errorf("errors.As did not return the last ApiErrorResponse")
+	}
+	if err = (&RetryTimeoutError{}); !errors.Is(err, ErrRetryTimeout) || err.Error() != ErrRetryTimeout.Error() {
+		t.Errorf("unexpected error without a last attempt: %v", err)
+	}
+}
@@ -64,3 +64,8 @@ func (e *SystemEvents) List(ro *SystemEventsRequest) ([]*SystemEvent, *ApiErrorR
 
 	return resp, nil, nil
 }
+
+func (e *SystemEvents) ListE(ro *SystemEventsRequest) ([]*SystemEvent, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
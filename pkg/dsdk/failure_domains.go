@@ -45,6 +45,11 @@ func (e *FailureDomains) Create(ro *FailureDomainsCreateRequest) (*FailureDomain
 	return resp, nil, nil
 }
 
+func (e *FailureDomains) CreateE(ro *FailureDomainsCreateRequest) (*FailureDomain, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type FailureDomainsListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -73,6 +78,11 @@ func (e *FailureDomains) List(ro *FailureDomainsListRequest) ([]*FailureDomain,
 	return resp, nil, nil
 }
 
+func (e *FailureDomains) ListE(ro *FailureDomainsListRequest) ([]*FailureDomain, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type FailureDomainsGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Id   string          `json:"-"`
@@ -94,6 +104,11 @@ func (e *FailureDomains) Get(ro *FailureDomainsGetRequest) (*FailureDomain, *Api
 	return resp, nil, nil
 }
 
+func (e *FailureDomains) GetE(ro *FailureDomainsGetRequest) (*FailureDomain, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type FailureDomainSetRequest struct {
 	Ctxt         context.Context `json:"-"`
 	StorageNodes []StorageNode   `json:"storage_nodes,omitempty" mapstructure:"storage_nodes"`
@@ -116,6 +131,11 @@ func (e *FailureDomain) Set(ro *FailureDomainSetRequest) (*FailureDomain, *ApiEr
 
 }
 
+func (e *FailureDomain) SetE(ro *FailureDomainSetRequest) (*FailureDomain, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type FailureDomainDeleteRequest struct {
 	Ctxt context.Context `json:"-"`
 	Name string          `json:"id,omitempty" mapstructure:"id"`
@@ -135,3 +155,8 @@ func (e *FailureDomain) Delete(ro *FailureDomainDeleteRequest) (*FailureDomain,
 	}
 	return resp, nil, nil
 }
+
+func (e *FailureDomain) DeleteE(ro *FailureDomainDeleteRequest) (*FailureDomain, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
@@ -0,0 +1,124 @@
+package dsdk
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	greq "github.com/levigross/grequests"
+)
+
+// flightCall is a read shared by every caller making it at the same time
+type flightCall struct {
+	done   chan struct{}
+	path   string
+	value  interface{}
+	apierr *ApiErrorResponse
+	err    error
+}
+
+// flightGroup coalesces identical concurrent reads into a single request
+type flightGroup struct {
+	m     sync.Mutex
+	calls map[string]*flightCall
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: map[string]*flightCall{}}
+}
+
+// requestKey identifies a read by tenant, path and params
+func (c *ApiConnection) requestKey(ctxt context.Context, url string, ro *greq.RequestOptions) string {
+	c.m.RLock()
+	tenant := c.tenant
+	c.m.RUnlock()
+	if t, ok := tenantFrom(ctxt); ok {
+		tenant = t
+	}
+	key := tenant + "|" + cachePath(url)
+	if ro != nil && len(ro.Params) > 0 {
+		params := make([]string, 0, len(ro.Params))
+		for k, v := range ro.Params {
+			params = append(params, k+"="+v)
+		}
+		sort.Strings(params)
+		key += "?" + strings.Join(params, "&")
+	}
+	return key
+}
+
+// coalesce runs fetch unless an identical read is already in flight, in which
+// case its result is shared.  Every caller gets its own copy of the result
+func (c *ApiConnection) coalesce(ctxt context.Context, url string, ro *greq.RequestOptions, fetch func() (interface{}, *ApiErrorResponse, error)) (interface{}, *ApiErrorResponse, error) {
+	// endpoint calls get a nil connection from GetConn when their context
+	// doesn't have one
+	if c == nil || c.flight == nil {
+		return fetch()
+	}
+	g := c.flight
+	key := c.requestKey(ctxt, url, ro)
+	for {
+		g.m.Lock()
+		call, ok := g.calls[key]
+		if !ok {
+			call = &flightCall{done: make(chan struct{}), path: cachePath(url)}
+			g.calls[key] = call
+			g.m.Unlock()
+
+			call.value, call.apierr, call.err = fetch()
+			g.m.Lock()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+			g.m.Unlock()
+			close(call.done)
+			return call.result()
+		}
+		g.m.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctxt.Done():
+			return nil, nil, ctxt.Err()
+		}
+		if (call.err == context.Canceled || call.err == context.DeadlineExceeded) && ctxt.Err() == nil {
+			// whoever started the read gave up on it, but we haven't
+			continue
+		}
+		return call.result()
+	}
+}
+
+func (call *flightCall) result() (interface{}, *ApiErrorResponse, error) {
+	var apierr *ApiErrorResponse
+	if call.apierr != nil {
+		e := *call.apierr
+		apierr = &e
+	}
+	return copyResponse(call.value), apierr, call.err
+}
+
+// invalidate forgets what is cached or in flight for url after a mutation
+func (c *ApiConnection) invalidate(url string) {
+	if c == nil {
+		return
+	}
+	if c.flight != nil {
+		c.flight.forget(url)
+	}
+	c.cacheInvalidate(url)
+}
+
+// forget makes reads of url, its parents and its children started after a
+// mutation not share the requests that were in flight during it
+func (g *flightGroup) forget(url string) {
+	p := cachePath(url)
+	g.m.Lock()
+	defer g.m.Unlock()
+	for k, call := range g.calls {
+		if call.path == p || isParentPath(call.path, p) || isParentPath(p, call.path) {
+			delete(g.calls, k)
+		}
+	}
+}
@@ -46,6 +46,11 @@ func (e *InitiatorGroups) Create(ro *InitiatorGroupsCreateRequest) (*InitiatorGr
 	return resp, nil, nil
 }
 
+func (e *InitiatorGroups) CreateE(ro *InitiatorGroupsCreateRequest) (*InitiatorGroup, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type InitiatorGroupsListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -74,6 +79,11 @@ func (e *InitiatorGroups) List(ro *InitiatorGroupsListRequest) ([]*InitiatorGrou
 	return resp, nil, nil
 }
 
+func (e *InitiatorGroups) ListE(ro *InitiatorGroupsListRequest) ([]*InitiatorGroup, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type InitiatorGroupsGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Name string          `json:"-"`
@@ -95,6 +105,11 @@ func (e *InitiatorGroups) Get(ro *InitiatorGroupsGetRequest) (*InitiatorGroup, *
 	return resp, nil, nil
 }
 
+func (e *InitiatorGroups) GetE(ro *InitiatorGroupsGetRequest) (*InitiatorGroup, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type InitiatorGroupSetRequest struct {
 	Ctxt    context.Context `json:"-"`
 	Members []Initiator     `json:"members,omitempty" mapstructure:"members"`
@@ -117,6 +132,11 @@ func (e *InitiatorGroup) Set(ro *InitiatorGroupSetRequest) (*InitiatorGroup, *Ap
 
 }
 
+func (e *InitiatorGroup) SetE(ro *InitiatorGroupSetRequest) (*InitiatorGroup, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type InitiatorGroupDeleteRequest struct {
 	Ctxt context.Context `json:"-"`
 	Id   string          `json:"id,omitempty" mapstructure:"id"`
@@ -136,3 +156,8 @@ func (e *InitiatorGroup) Delete(ro *InitiatorGroupDeleteRequest) (*InitiatorGrou
 	}
 	return resp, nil, nil
 }
+
+func (e *InitiatorGroup) DeleteE(ro *InitiatorGroupDeleteRequest) (*InitiatorGroup, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
@@ -33,7 +33,7 @@ func newInitiators(path string) *Initiators {
 
 func (e *Initiators) Create(ro *InitiatorsCreateRequest) (*Initiator, *ApiErrorResponse, error) {
 	gro := &greq.RequestOptions{JSON: ro}
-	rs, apierr, err := GetConn(ro.Ctxt).Post(ro.Ctxt, e.Path, gro)
+	rs, _, apierr, err := GetConn(ro.Ctxt).CreateOrAdopt(ro.Ctxt, e.Path, ro.Id, gro)
 	if apierr != nil {
 		return nil, apierr, err
 	}
@@ -47,6 +47,11 @@ func (e *Initiators) Create(ro *InitiatorsCreateRequest) (*Initiator, *ApiErrorR
 	return resp, nil, nil
 }
 
+func (e *Initiators) CreateE(ro *InitiatorsCreateRequest) (*Initiator, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type InitiatorsListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -75,6 +80,11 @@ func (e *Initiators) List(ro *InitiatorsListRequest) ([]*Initiator, *ApiErrorRes
 	return resp, nil, nil
 }
 
+func (e *Initiators) ListE(ro *InitiatorsListRequest) ([]*Initiator, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type InitiatorsGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Id   string          `json:"-"`
@@ -96,6 +106,11 @@ func (e *Initiators) Get(ro *InitiatorsGetRequest) (*Initiator, *ApiErrorRespons
 	return resp, nil, nil
 }
 
+func (e *Initiators) GetE(ro *InitiatorsGetRequest) (*Initiator, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type InitiatorSetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Name string          `json:"name,omitempty" mapstructure:"name"`
@@ -118,6 +133,11 @@ func (e *Initiator) Set(ro *InitiatorSetRequest) (*Initiator, *ApiErrorResponse,
 
 }
 
+func (e *Initiator) SetE(ro *InitiatorSetRequest) (*Initiator, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type InitiatorDeleteRequest struct {
 	Ctxt context.Context `json:"-"`
 	Id   string          `json:"id,omitempty" mapstructure:"id"`
@@ -137,3 +157,8 @@ func (e *Initiator) Delete(ro *InitiatorDeleteRequest) (*Initiator, *ApiErrorRes
 	}
 	return resp, nil, nil
 }
+
+func (e *Initiator) DeleteE(ro *InitiatorDeleteRequest) (*Initiator, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
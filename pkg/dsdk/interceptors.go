@@ -0,0 +1,155 @@
+package dsdk
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Call is a single HTTP exchange between the SDK and the Datera API as seen by
+// an Interceptor.  Interceptors may modify the request fields before calling
+// the next Invoker, and the response fields after it returns.  An Interceptor
+// that doesn't call next must fill in the response fields itself.
+type Call struct {
+	Context context.Context
+	Method  string
+	URL     *url.URL
+	// Route is the canonical form of URL.Path, eg. /v2.2/app_instances/:id
+	Route  string
+	Header http.Header
+	Body   []byte
+
+	StatusCode     int
+	ResponseHeader http.Header
+	ResponseBody   []byte
+	// Latency is the time spent waiting on the API, not including interceptors
+	Latency time.Duration
+	// Err is the transport error of the request, if any
+	Err error
+}
+
+// Invoker performs a Call
+type Invoker func(call *Call) error
+
+// Interceptor wraps every HTTP request made by an ApiConnection, including
+// logins and log uploads.  It must call next to actually send the request
+type Interceptor func(call *Call, next Invoker) error
+
+// AddInterceptor appends interceptors to the chain of the connection.  The
+// first interceptor added is the outermost one
+func (c *ApiConnection) AddInterceptor(i ...Interceptor) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.interceptors = append(c.interceptors, i...)
+}
+
+// wrapClient returns a copy of client whose requests go through the
+// interceptor chain of the connection
+func (c *ApiConnection) wrapClient(client *http.Client) *http.Client {
+	nc := &http.Client{}
+	if client != nil {
+		*nc = *client
+	}
+	nc.Transport = &interceptTransport{conn: c, base: nc.Transport}
+	return nc
+}
+
+type interceptTransport struct {
+	conn *ApiConnection
+	// base is looked up on every request when nil so that changes to
+	// http.DefaultTransport are respected
+	base http.RoundTripper
+}
+
+func (t *interceptTransport) baseTransport() http.RoundTripper {
+	if t.base != nil {
+		return t.base
+	}
+	return http.DefaultTransport
+}
+
+func (t *interceptTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.conn.m.RLock()
+	chain := t.conn.interceptors
+	t.conn.m.RUnlock()
+	if len(chain) == 0 {
+		return t.baseTransport().RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	u := *req.URL
+	call := &Call{
+		Context: req.Context(),
+		Method:  req.Method,
+		URL:     &u,
+		Route:   canonicalizeRoute(u.Path, t.conn.ApiVersion()),
+		Header:  req.Header.Clone(),
+		Body:    body,
+	}
+
+	invoke := t.invoke
+	for i := len(chain) - 1; i >= 0; i-- {
+		interceptor, next := chain[i], invoke
+		invoke = func(call *Call) error {
+			return interceptor(call, next)
+		}
+	}
+	if err := invoke(call); err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", call.StatusCode, http.StatusText(call.StatusCode)),
+		StatusCode:    call.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        call.ResponseHeader,
+		Body:          ioutil.NopCloser(bytes.NewReader(call.ResponseBody)),
+		ContentLength: int64(len(call.ResponseBody)),
+		Request:       req,
+	}, nil
+}
+
+// invoke is the innermost Invoker which actually sends the request
+func (t *interceptTransport) invoke(call *Call) error {
+	req, err := http.NewRequest(call.Method, call.URL.String(), bytes.NewReader(call.Body))
+	if err != nil {
+		call.Err = err
+		return err
+	}
+	req = req.WithContext(call.Context)
+	req.Header = call.Header
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+
+	t1 := time.Now()
+	resp, err := t.baseTransport().RoundTrip(req)
+	if err != nil {
+		call.Latency = time.Since(t1)
+		call.Err = err
+		return err
+	}
+	defer resp.Body.Close()
+	call.ResponseBody, err = ioutil.ReadAll(resp.Body)
+	call.Latency = time.Since(t1)
+	call.StatusCode = resp.StatusCode
+	call.ResponseHeader = resp.Header
+	if err != nil {
+		call.Err = err
+	}
+	return err
+}
@@ -47,6 +47,11 @@ func (e *AccessNetworkIpPools) Create(ro *AccessNetworkIpPoolsCreateRequest) (*A
 	return resp, nil, nil
 }
 
+func (e *AccessNetworkIpPools) CreateE(ro *AccessNetworkIpPoolsCreateRequest) (*AccessNetworkIpPool, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AccessNetworkIpPoolsListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -75,6 +80,11 @@ func (e *AccessNetworkIpPools) List(ro *AccessNetworkIpPoolsListRequest) ([]*Acc
 	return resp, nil, nil
 }
 
+func (e *AccessNetworkIpPools) ListE(ro *AccessNetworkIpPoolsListRequest) ([]*AccessNetworkIpPool, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AccessNetworkIpPoolsGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Name string          `json:"-"`
@@ -96,6 +106,11 @@ func (e *AccessNetworkIpPools) Get(ro *AccessNetworkIpPoolsGetRequest) (*AccessN
 	return resp, nil, nil
 }
 
+func (e *AccessNetworkIpPools) GetE(ro *AccessNetworkIpPoolsGetRequest) (*AccessNetworkIpPool, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AccessNetworkIpPoolSetRequest struct {
 	Ctxt    context.Context `json:"-"`
 	Members []Initiator     `json:"members,omitempty" mapstructure:"members"`
@@ -118,6 +133,11 @@ func (e *AccessNetworkIpPool) Set(ro *AccessNetworkIpPoolSetRequest) (*AccessNet
 
 }
 
+func (e *AccessNetworkIpPool) SetE(ro *AccessNetworkIpPoolSetRequest) (*AccessNetworkIpPool, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type AccessNetworkIpPoolDeleteRequest struct {
 	Ctxt context.Context `json:"-"`
 	Id   string          `json:"id,omitempty" mapstructure:"id"`
@@ -137,3 +157,8 @@ func (e *AccessNetworkIpPool) Delete(ro *AccessNetworkIpPoolDeleteRequest) (*Acc
 	}
 	return resp, nil, nil
 }
+
+func (e *AccessNetworkIpPool) DeleteE(ro *AccessNetworkIpPoolDeleteRequest) (*AccessNetworkIpPool, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
@@ -0,0 +1,282 @@
+package dsdk
+
+import (
+	"context"
+	"strconv"
+
+	greq "github.com/levigross/grequests"
+)
+
+// ListIterator walks a list endpoint one page at a time instead of fetching
+// every page up front like GetList does.  Pages are only requested once the
+// previous one has been consumed, so stopping early is just a matter of no
+// longer calling Next
+//
+//	it := sdk.AppInstances.Iter(ctxt, dsdk.ListParams{Limit: 100})
+//	for it.Next() {
+//		ai := it.Value()
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type ListIterator struct {
+	ctxt    context.Context
+	conn    *ApiConnection
+	url     string
+	params  map[string]string
+	offset  int
+	convert func(map[string]interface{}) (interface{}, error)
+
+	page  []interface{}
+	i     int
+	total int
+	last  bool
+	value interface{}
+
+	apierr *ApiErrorResponse
+	err    error
+}
+
+// Iter returns an iterator over the entries of the list endpoint at url.  The
+// offset and limit params pick where to start and how many entries to request
+// per page, any other params are sent with every request.  Values are the raw
+// map[string]interface{} entries
+func (c *ApiConnection) Iter(ctxt context.Context, url string, ro *greq.RequestOptions) *ListIterator {
+	var params map[string]string
+	if ro != nil {
+		params = ro.Params
+	}
+	return newListIterator(ctxt, c, url, params, nil)
+}
+
+func newListIterator(ctxt context.Context, conn *ApiConnection, url string, params map[string]string, convert func(map[string]interface{}) (interface{}, error)) *ListIterator {
+	it := &ListIterator{
+		ctxt:    ctxt,
+		conn:    conn,
+		url:     url,
+		params:  map[string]string{},
+		convert: convert,
+		total:   -1,
+	}
+	for k, v := range params {
+		it.params[k] = v
+	}
+	if conn == nil {
+		it.err = ErrNoConnection
+		return it
+	}
+	if o := it.params["offset"]; o != "" {
+		offset, err := strconv.Atoi(o)
+		if err != nil {
+			it.err = err
+			return it
+		}
+		it.offset = offset
+	}
+	return it
+}
+
+// Next advances to the next entry, fetching the next page when needed.  It
+// returns false once every entry was returned, the context is done or a
+// request failed, see Err
+func (it *ListIterator) Next() bool {
+	it.value = nil
+	if it.err != nil || it.apierr != nil {
+		return false
+	}
+	if err := it.ctxt.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	for it.i >= len(it.page) {
+		if it.last {
+			return false
+		}
+		if !it.fetch() {
+			return false
+		}
+	}
+	data := it.page[it.i]
+	it.i++
+	if it.convert == nil {
+		it.value = data
+		return true
+	}
+	adata, _ := data.(map[string]interface{})
+	if it.value, it.err = it.convert(adata); it.err != nil {
+		it.value = nil
+		return false
+	}
+	return true
+}
+
+func (it *ListIterator) fetch() bool {
+	it.params["offset"] = strconv.Itoa(it.offset)
+	rs := &ApiListOuter{}
+	it.apierr, it.err = it.conn.doWithAuth(it.ctxt, "GET", it.url, &greq.RequestOptions{Params: it.params}, rs)
+	if it.apierr != nil || it.err != nil {
+		return false
+	}
+	it.page, it.i = rs.Data, 0
+	it.offset += len(rs.Data)
+	if tcnt, ok := rs.Metadata["total_count"].(float64); ok {
+		it.total = int(tcnt)
+	}
+	// endpoints without a total_count aren't paged
+	if it.total < 0 || it.offset >= it.total || len(rs.Data) == 0 {
+		it.last = true
+	}
+	return len(it.page) > 0
+}
+
+// Value returns the entry Next advanced to
+func (it *ListIterator) Value() interface{} {
+	return it.value
+}
+
+// Err returns why the iteration stopped early, if it did.  API errors can be
+// inspected with errors.As as with the E suffixed endpoint methods
+func (it *ListIterator) Err() error {
+	return AsError(it.apierr, it.err)
+}
+
+// Total returns the number of entries the cluster reported for the list, -1
+// until the first page was fetched or if the endpoint doesn't report it
+func (it *ListIterator) Total() int {
+	return it.total
+}
+
+// AppInstanceIterator iterates over AppInstances, see ListIterator
+type AppInstanceIterator struct {
+	*ListIterator
+}
+
+// Value returns the AppInstance Next advanced to
+func (it AppInstanceIterator) Value() *AppInstance {
+	v, _ := it.ListIterator.Value().(*AppInstance)
+	return v
+}
+
+// Iter returns an iterator fetching AppInstances a page at a time
+func (e *AppInstances) Iter(ctxt context.Context, params ListParams) AppInstanceIterator {
+	return AppInstanceIterator{newListIterator(ctxt, GetConn(ctxt), e.Path, params.ToMap(), func(data map[string]interface{}) (interface{}, error) {
+		elem := &AppInstance{}
+		if err := FillStruct(data, elem); err != nil {
+			return nil, err
+		}
+		RegisterAppInstanceEndpoints(elem)
+		return elem, nil
+	})}
+}
+
+// StorageInstanceIterator iterates over StorageInstances, see ListIterator
+type StorageInstanceIterator struct {
+	*ListIterator
+}
+
+// Value returns the StorageInstance Next advanced to
+func (it StorageInstanceIterator) Value() *StorageInstance {
+	v, _ := it.ListIterator.Value().(*StorageInstance)
+	return v
+}
+
+// Iter returns an iterator fetching StorageInstances a page at a time
+func (e *StorageInstances) Iter(ctxt context.Context, params ListParams) StorageInstanceIterator {
+	return StorageInstanceIterator{newListIterator(ctxt, GetConn(ctxt), e.Path, params.ToMap(), func(data map[string]interface{}) (interface{}, error) {
+		elem := &StorageInstance{}
+		if err := FillStruct(data, elem); err != nil {
+			return nil, err
+		}
+		RegisterStorageInstanceEndpoints(elem)
+		return elem, nil
+	})}
+}
+
+// VolumeIterator iterates over Volumes, see ListIterator
+type VolumeIterator struct {
+	*ListIterator
+}
+
+// Value returns the Volume Next advanced to
+func (it VolumeIterator) Value() *Volume {
+	v, _ := it.ListIterator.Value().(*Volume)
+	return v
+}
+
+// Iter returns an iterator fetching Volumes a page at a time
+func (e *Volumes) Iter(ctxt context.Context, params ListParams) VolumeIterator {
+	return VolumeIterator{newListIterator(ctxt, GetConn(ctxt), e.Path, params.ToMap(), func(data map[string]interface{}) (interface{}, error) {
+		elem := &Volume{}
+		if err := FillStruct(data, elem); err != nil {
+			return nil, err
+		}
+		RegisterVolumeEndpoints(elem)
+		return elem, nil
+	})}
+}
+
+// SnapshotIterator iterates over Snapshots, see ListIterator
+type SnapshotIterator struct {
+	*ListIterator
+}
+
+// Value returns the Snapshot Next advanced to
+func (it SnapshotIterator) Value() *Snapshot {
+	v, _ := it.ListIterator.Value().(*Snapshot)
+	return v
+}
+
+// Iter returns an iterator fetching Snapshots a page at a time
+func (e *Snapshots) Iter(ctxt context.Context, params ListParams) SnapshotIterator {
+	return SnapshotIterator{newListIterator(ctxt, GetConn(ctxt), e.Path, params.ToMap(), func(data map[string]interface{}) (interface{}, error) {
+		elem := &Snapshot{}
+		if err := FillStruct(data, elem); err != nil {
+			return nil, err
+		}
+		return elem, nil
+	})}
+}
+
+// InitiatorIterator iterates over Initiators, see ListIterator
+type InitiatorIterator struct {
+	*ListIterator
+}
+
+// Value returns the Initiator Next advanced to
+func (it InitiatorIterator) Value() *Initiator {
+	v, _ := it.ListIterator.Value().(*Initiator)
+	return v
+}
+
+// Iter returns an iterator fetching Initiators a page at a time
+func (e *Initiators) Iter(ctxt context.Context, params ListParams) InitiatorIterator {
+	return InitiatorIterator{newListIterator(ctxt, GetConn(ctxt), e.Path, params.ToMap(), func(data map[string]interface{}) (interface{}, error) {
+		elem := &Initiator{}
+		if err := FillStruct(data, elem); err != nil {
+			return nil, err
+		}
+		return elem, nil
+	})}
+}
+
+// InitiatorGroupIterator iterates over InitiatorGroups, see ListIterator
+type InitiatorGroupIterator struct {
+	*ListIterator
+}
+
+// Value returns the InitiatorGroup Next advanced to
+func (it InitiatorGroupIterator) Value() *InitiatorGroup {
+	v, _ := it.ListIterator.Value().(*InitiatorGroup)
+	return v
+}
+
+// Iter returns an iterator fetching InitiatorGroups a page at a time
+func (e *InitiatorGroups) Iter(ctxt context.Context, params ListParams) InitiatorGroupIterator {
+	return InitiatorGroupIterator{newListIterator(ctxt, GetConn(ctxt), e.Path, params.ToMap(), func(data map[string]interface{}) (interface{}, error) {
+		elem := &InitiatorGroup{}
+		if err := FillStruct(data, elem); err != nil {
+			return nil, err
+		}
+		return elem, nil
+	})}
+}
@@ -0,0 +1,194 @@
+package dsdk
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LogFields are structured key/values attached to log messages
+type LogFields map[string]interface{}
+
+// Logger is used for everything the SDK logs.  NewLogrusLogger,
+// NewZapLogger, NewSlogLogger and NopLogger adapt common loggers to it
+type Logger interface {
+	WithFields(fields LogFields) Logger
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	// DebugEnabled reports whether debug messages are logged.  Request and
+	// response payloads are only formatted for logging when it's true
+	DebugEnabled() bool
+}
+
+// defaultLogger is used by connections without a Logger and by code that
+// isn't tied to a connection
+var defaultLogger Logger = NewLogrusLogger(log.StandardLogger())
+
+// SetDefaultLogger replaces the logger used by connections that don't have
+// their own, which is the global logrus logger unless changed
+func SetDefaultLogger(l Logger) {
+	if l == nil {
+		l = NopLogger
+	}
+	defaultLogger = l
+}
+
+// SetLogger sets the logger of the connection.  It should be called before
+// the connection is shared between goroutines
+func (c *ApiConnection) SetLogger(l Logger) {
+	c.logger = l
+}
+
+// log returns the logger of the connection decorated with the user fields
+// of ctxt and where it was called from.  c may be nil
+func (c *ApiConnection) log(ctxt context.Context) Logger {
+	l := defaultLogger
+	if c != nil && c.logger != nil {
+		l = c.logger
+	}
+	return decorate(ctxt, l)
+}
+
+// defaultLog is log for code without a connection or context
+func defaultLog() Logger {
+	return decorate(context.Background(), defaultLogger)
+}
+
+func decorate(ctxt context.Context, l Logger) Logger {
+	fields := LogFields{}
+	// skip decorate and log or defaultLog
+	if pc, file, line, ok := runtime.Caller(2); ok {
+		fields["file"] = file
+		fields["line"] = line
+		fields["func"] = runtime.FuncForPC(pc).Name()
+	}
+	if ctxt != nil {
+		if userFields, ok := ctxt.Value(UserLogFieldsCtxKey).(map[string]interface{}); ok {
+			for k, v := range userFields {
+				fields[k] = v
+			}
+		}
+	}
+	return l.WithFields(fields)
+}
+
+type logrusLogger struct {
+	l log.FieldLogger
+}
+
+// NewLogrusLogger adapts a logrus Logger or Entry
+func NewLogrusLogger(l log.FieldLogger) Logger {
+	return logrusLogger{l: l}
+}
+
+func (l logrusLogger) WithFields(fields LogFields) Logger {
+	return logrusLogger{l: l.l.WithFields(log.Fields(fields))}
+}
+
+func (l logrusLogger) Debugf(format string, args ...interface{}) { l.l.Debugf(format, args...) }
+func (l logrusLogger) Infof(format string, args ...interface{})  { l.l.Infof(format, args...) }
+func (l logrusLogger) Warnf(format string, args ...interface{})  { l.l.Warnf(format, args...) }
+func (l logrusLogger) Errorf(format string, args ...interface{}) { l.l.Errorf(format, args...) }
+
+func (l logrusLogger) DebugEnabled() bool {
+	switch t := l.l.(type) {
+	case *log.Logger:
+		return t.IsLevelEnabled(log.DebugLevel)
+	case *log.Entry:
+		return t.Logger.IsLevelEnabled(log.DebugLevel)
+	}
+	return true
+}
+
+// ZapSugaredLogger is the subset of *zap.SugaredLogger used by NewZapLogger
+type ZapSugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// NewZapLogger adapts a zap SugaredLogger, eg. NewZapLogger(zl.Sugar())
+func NewZapLogger(l ZapSugaredLogger) Logger {
+	return kvLogger{
+		debug: l.Debugw,
+		info:  l.Infow,
+		warn:  l.Warnw,
+		error: l.Errorw,
+	}
+}
+
+// SlogLogger is the subset of *slog.Logger used by NewSlogLogger
+type SlogLogger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// NewSlogLogger adapts a log/slog Logger, or any logger with the same
+// methods
+func NewSlogLogger(l SlogLogger) Logger {
+	return kvLogger{
+		debug: l.Debug,
+		info:  l.Info,
+		warn:  l.Warn,
+		error: l.Error,
+	}
+}
+
+// kvLogger adapts loggers that take a message followed by alternating keys
+// and values
+type kvLogger struct {
+	debug, info, warn, error func(msg string, keysAndValues ...interface{})
+	kvs                      []interface{}
+}
+
+func (l kvLogger) WithFields(fields LogFields) Logger {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	// keep the output stable
+	sort.Strings(keys)
+	kvs := make([]interface{}, len(l.kvs), len(l.kvs)+2*len(keys))
+	copy(kvs, l.kvs)
+	for _, k := range keys {
+		kvs = append(kvs, k, fields[k])
+	}
+	l.kvs = kvs
+	return l
+}
+
+func (l kvLogger) Debugf(format string, args ...interface{}) {
+	l.debug(fmt.Sprintf(format, args...), l.kvs...)
+}
+func (l kvLogger) Infof(format string, args ...interface{}) {
+	l.info(fmt.Sprintf(format, args...), l.kvs...)
+}
+func (l kvLogger) Warnf(format string, args ...interface{}) {
+	l.warn(fmt.Sprintf(format, args...), l.kvs...)
+}
+func (l kvLogger) Errorf(format string, args ...interface{}) {
+	l.error(fmt.Sprintf(format, args...), l.kvs...)
+}
+
+// DebugEnabled is always true, the adapted logger decides what to drop
+func (l kvLogger) DebugEnabled() bool { return true }
+
+type nopLogger struct{}
+
+// NopLogger discards everything
+var NopLogger Logger = nopLogger{}
+
+func (l nopLogger) WithFields(fields LogFields) Logger        { return l }
+func (l nopLogger) Debugf(format string, args ...interface{}) {}
+func (l nopLogger) Infof(format string, args ...interface{})  {}
+func (l nopLogger) Warnf(format string, args ...interface{})  {}
+func (l nopLogger) Errorf(format string, args ...interface{}) {}
+func (l nopLogger) DebugEnabled() bool                        { return false }
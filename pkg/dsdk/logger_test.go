@@ -0,0 +1,121 @@
+package dsdk
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type kvRecord struct {
+	level string
+	msg   string
+	kvs   []interface{}
+}
+
+// kvRecorder has the methods of both a slog and a zap sugared logger
+type kvRecorder struct {
+	records []kvRecord
+}
+
+func (r *kvRecorder) add(level, msg string, kvs []interface{}) {
+	r.records = append(r.records, kvRecord{level: level, msg: msg, kvs: kvs})
+}
+
+func (r *kvRecorder) Debug(msg string, args ...interface{}) { r.add("debug", msg, args) }
+func (r *kvRecorder) Info(msg string, args ...interface{})  { r.add("info", msg, args) }
+func (r *kvRecorder) Warn(msg string, args ...interface{})  { r.add("warn", msg, args) }
+func (r *kvRecorder) Error(msg string, args ...interface{}) { r.add("error", msg, args) }
+
+func (r *kvRecorder) Debugw(msg string, kvs ...interface{}) { r.add("debug", msg, kvs) }
+func (r *kvRecorder) Infow(msg string, kvs ...interface{})  { r.add("info", msg, kvs) }
+func (r *kvRecorder) Warnw(msg string, kvs ...interface{})  { r.add("warn", msg, kvs) }
+func (r *kvRecorder) Errorw(msg string, kvs ...interface{}) { r.add("error", msg, kvs) }
+
+func TestKVLoggers(t *testing.T) {
+	for name, newLogger := range map[string]func(*kvRecorder) Logger{
+		"slog": func(r *kvRecorder) Logger { return NewSlogLogger(r) },
+		"zap":  func(r *kvRecorder) Logger { return NewZapLogger(r) },
+	} {
+		r := &kvRecorder{}
+		l := newLogger(r).WithFields(LogFields{"b": 2, "a": 1})
+		l.WithFields(LogFields{"c": 3}).Warnf("hello %s", "world")
+		l.Errorf("failed %d", 42)
+
+		if len(r.records) != 2 {
+			t.Fatalf("%s: expected 2 records, got %d", name, len(r.records))
+		}
+		got := fmt.Sprint(r.records[0])
+		if want := "{warn hello world [a 1 b 2 c 3]}"; got != want {
+			t.Errorf("%s: expected %s, got %s", name, want, got)
+		}
+		// fields added to a derived logger don't leak into its parent
+		got = fmt.Sprint(r.records[1])
+		if want := "{error failed 42 [a 1 b 2]}"; got != want {
+			t.Errorf("%s: expected %s, got %s", name, want, got)
+		}
+	}
+}
+
+func TestLogrusLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ll := log.New()
+	ll.SetOutput(buf)
+	ll.SetLevel(log.InfoLevel)
+	l := NewLogrusLogger(ll)
+	if l.DebugEnabled() {
+		t.Error("expected debug to be disabled")
+	}
+	l.WithFields(LogFields{"foo": "bar"}).Debugf("dropped")
+	l.WithFields(LogFields{"foo": "bar"}).Infof("kept")
+	if out := buf.String(); strings.Contains(out, "dropped") || !strings.Contains(out, "foo=bar") {
+		t.Errorf("unexpected output %q", out)
+	}
+	ll.SetLevel(log.DebugLevel)
+	if !l.WithFields(LogFields{}).DebugEnabled() {
+		t.Error("expected debug to be enabled")
+	}
+	if NopLogger.DebugEnabled() {
+		t.Error("expected NopLogger to have debug disabled")
+	}
+}
+
+func TestConnectionLogger(t *testing.T) {
+	r := &kvRecorder{}
+	conn := &ApiConnection{}
+	conn.SetLogger(NewSlogLogger(r))
+	ctxt := context.WithValue(context.Background(), UserLogFieldsCtxKey, map[string]interface{}{"user": "field"})
+	conn.log(ctxt).Infof("hello")
+
+	if len(r.records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(r.records))
+	}
+	fields := map[interface{}]interface{}{}
+	kvs := r.records[0].kvs
+	for i := 0; i+1 < len(kvs); i += 2 {
+		fields[kvs[i]] = kvs[i+1]
+	}
+	if fields["user"] != "field" {
+		t.Errorf("expected the user fields, got %v", fields)
+	}
+	if f, _ := fields["func"].(string); !strings.HasSuffix(f, "TestConnectionLogger") {
+		t.Errorf("expected the caller, got %v", fields["func"])
+	}
+	if f, _ := fields["file"].(string); !strings.HasSuffix(f, "logger_test.go") {
+		t.Errorf("expected the caller's file, got %v", fields["file"])
+	}
+
+	// connections without a logger use the default one
+	old := defaultLogger
+	defer SetDefaultLogger(old)
+	r = &kvRecorder{}
+	SetDefaultLogger(NewZapLogger(r))
+	var nilConn *ApiConnection
+	nilConn.log(nil).Debugf("default")
+	if len(r.records) != 1 || r.records[0].msg != "default" {
+		t.Errorf("expected the default logger to be used, got %v", r.records)
+	}
+}
@@ -13,7 +13,6 @@ import (
 	_path "path"
 
 	uuid "github.com/google/uuid"
-	log "github.com/sirupsen/logrus"
 )
 
 var (
@@ -36,21 +35,20 @@ func newLogsUpload(path string) *LogsUpload {
 }
 
 func logsUpload(ctxt context.Context, file string) error {
-	conn := GetConn(ctxt)
-	tid, ok := ctxt.Value("tid").(string)
-	if !ok {
-		tid = "nil"
+	conn, err := ConnectionFrom(ctxt)
+	if err != nil {
+		return err
 	}
+	tid := TraceIDFrom(ctxt)
 	reqId := uuid.Must(uuid.NewRandom()).String()
-	var err error
 	if conn.apikey == "" {
 		if _, err = conn.Login(ctxt); err != nil {
 			return err
 		}
 	}
 	key := conn.apikey
-	gurl := *conn.baseUrl
-	gurl.Path = _path.Join(conn.baseUrl.Path, "logs_upload")
+	gurl := *conn.endpoints.pick()
+	gurl.Path = _path.Join(gurl.Path, "logs_upload")
 	url := gurl.String()
 
 	var b bytes.Buffer
@@ -82,34 +80,35 @@ func logsUpload(ctxt context.Context, file string) error {
 	if err != nil {
 		return err
 	}
+	req = req.WithContext(ctxt)
 	// Don't forget to set the content type, this will contain the boundary.
 	req.Header.Set("Content-Type", w.FormDataContentType())
 	req.Header.Set("Auth-Token", key)
 
 	// Submit the request
-	client := &http.Client{}
-	sheaders, err := json.Marshal(req.Header)
+	client := conn.httpClient
+	sheaders, err := json.Marshal(redactHeaders(req.Header))
 	if err != nil {
-		Log().Errorf("Couldn't stringify headers, %s", req.Header)
+		conn.log(ctxt).Errorf("Couldn't stringify headers, %s", req.Header)
 	}
-	Log().WithFields(log.Fields{
+	conn.log(ctxt).WithFields(LogFields{
 		logTraceID:        tid,
 		"request_id":      reqId,
 		"request_method":  http.MethodPut,
 		"request_url":     gurl.String(),
 		"request_headers": sheaders,
-	}).Debug("Datera SDK uploading logs")
+	}).Debugf("Datera SDK uploading logs")
 	res, err := client.Do(req)
 	if err != nil {
 		return err
 	}
-	Log().Debugf("Status Code: %d", res.StatusCode)
+	conn.log(ctxt).Debugf("Status Code: %d", res.StatusCode)
 	// Check the response
 	if res.StatusCode != http.StatusOK {
 		err = fmt.Errorf("bad status: %s", res.Status)
 		bodyBytes, _ := ioutil.ReadAll(res.Body)
-		Log().Error(err)
-		Log().Error(string(bodyBytes))
+		conn.log(ctxt).Errorf("%s", err)
+		conn.log(ctxt).Errorf("%s", bodyBytes)
 		return err
 	}
 	return nil
@@ -126,6 +125,11 @@ func (e *LogsUpload) Upload(ro *LogsUploadRequest) (*LogsUpload, *ApiErrorRespon
 	return nil, nil, logsUpload(ro.Ctxt, ro.Files[0])
 }
 
+func (e *LogsUpload) UploadE(ro *LogsUploadRequest) (*LogsUpload, error) {
+	resp, apierr, err := e.Upload(ro)
+	return resp, AsError(apierr, err)
+}
+
 func (e *LogsUpload) RotateUploadRemove(ctxt context.Context, rule, rotated string) error {
 	if err := rotateLogs(rule); err != nil {
 		return err
@@ -144,19 +148,19 @@ func (e *LogsUpload) RotateUploadRemove(ctxt context.Context, rule, rotated stri
 	}
 	// Even a single line of logs will be greater than 100 bytes
 	if fstat.Size() > 100 {
-		Log().Debug("Uploading logs")
+		GetConn(ctxt).log(ctxt).Debugf("Uploading logs")
 		_, apierr, err := e.Upload(&LogsUploadRequest{
 			Ctxt:  ctxt,
 			Files: []string{rotated},
 		})
 		if apierr != nil {
-			Log().Errorf("%s", Pretty(apierr))
+			GetConn(ctxt).log(ctxt).Errorf("%s", Pretty(apierr))
 		}
 		if err != nil {
-			Log().Error(err)
+			GetConn(ctxt).log(ctxt).Errorf("%s", err)
 		}
 	} else {
-		Log().Debugf("No new filtered logs detected.  Size: %d", fstat.Size())
+		GetConn(ctxt).log(ctxt).Debugf("No new filtered logs detected.  Size: %d", fstat.Size())
 	}
 	return nil
 }
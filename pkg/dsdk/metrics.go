@@ -150,6 +150,11 @@ func (m *IOMetrics) List(ro *IOMetricsRequest) ([]*Metrics, *ApiErrorResponse, e
 	return resp, nil, nil
 }
 
+func (m *IOMetrics) ListE(ro *IOMetricsRequest) ([]*Metrics, error) {
+	resp, apierr, err := m.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 func (m *HWMetrics) List(ro *HWMetricsRequest) ([]*Metrics, *ApiErrorResponse, error) {
 	if err := ro.Type.Validate(); err != nil {
 		return nil, nil, err
@@ -181,3 +186,8 @@ func (m *HWMetrics) List(ro *HWMetricsRequest) ([]*Metrics, *ApiErrorResponse, e
 
 	return resp, nil, nil
 }
+
+func (m *HWMetrics) ListE(ro *HWMetricsRequest) ([]*Metrics, error) {
+	resp, apierr, err := m.List(ro)
+	return resp, AsError(apierr, err)
+}
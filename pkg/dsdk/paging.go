@@ -0,0 +1,118 @@
+package dsdk
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	greq "github.com/levigross/grequests"
+)
+
+// SetPageWorkers makes GetList fetch the pages after the first one with up to
+// n concurrent requests.  It should be called before the connection is shared
+// between goroutines.  A n of 0 or 1 fetches pages one at a time
+func (c *ApiConnection) SetPageWorkers(n int) {
+	c.pageWorkers = n
+}
+
+// WithPageWorkers returns a context that causes lists fetched with it to use
+// n concurrent requests instead of the number configured on the ApiConnection
+func WithPageWorkers(ctxt context.Context, n int) context.Context {
+	return context.WithValue(ctxt, pageWorkersCtxKey, n)
+}
+
+func (c *ApiConnection) pageWorkersFor(ctxt context.Context) int {
+	if n, ok := ctxt.Value(pageWorkersCtxKey).(int); ok {
+		return n
+	}
+	return c.pageWorkers
+}
+
+// TotalCount returns the number of entries the cluster reported for the list,
+// or -1 if it didn't.  It can be compared to len(Data) to tell whether every
+// entry was retrieved
+func (l *ApiListOuter) TotalCount() int {
+	if tcnt, ok := l.Metadata["total_count"].(float64); ok {
+		return int(tcnt)
+	}
+	return -1
+}
+
+// getPages fetches the entries of url from offset up to total with pages of
+// limit entries, using workers concurrent requests.  Entries are returned in
+// order.  The first failure cancels the outstanding requests, in which case
+// the entries of the pages before the failed one are returned with it
+func (c *ApiConnection) getPages(ctxt context.Context, url string, ro *greq.RequestOptions, offset, limit, total, workers int) ([]interface{}, *ApiErrorResponse, error) {
+	offsets := []int{}
+	for o := offset; o < total; o += limit {
+		offsets = append(offsets, o)
+	}
+	if workers > len(offsets) {
+		workers = len(offsets)
+	}
+	pages := make([][]interface{}, len(offsets))
+	failed := len(offsets)
+
+	wctxt, cancel := context.WithCancel(ctxt)
+	defer cancel()
+	var (
+		m      sync.Mutex
+		apierr *ApiErrorResponse
+		err    error
+		wg     sync.WaitGroup
+	)
+	jobs := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				pro := &greq.RequestOptions{JSON: ro.JSON, Params: map[string]string{}}
+				for k, v := range ro.Params {
+					pro.Params[k] = v
+				}
+				pro.Params["offset"] = strconv.Itoa(offsets[i])
+				pro.Params["limit"] = strconv.Itoa(limit)
+				rs := &ApiListOuter{}
+				papierr, perr := c.doWithAuth(wctxt, "GET", url, pro, rs)
+				m.Lock()
+				if papierr != nil || perr != nil {
+					// only the first failure is reported, the requests it
+					// cancels fail too
+					if apierr == nil && err == nil {
+						apierr, err = papierr, perr
+						cancel()
+					}
+					if i < failed {
+						failed = i
+					}
+				} else {
+					pages[i] = rs.Data
+				}
+				m.Unlock()
+			}
+		}()
+	}
+	sent := 0
+	for ; sent < len(offsets); sent++ {
+		if wctxt.Err() != nil {
+			break
+		}
+		jobs <- sent
+	}
+	close(jobs)
+	wg.Wait()
+
+	if sent < failed {
+		failed = sent
+	}
+	if apierr == nil && err == nil && sent < len(offsets) {
+		// ctxt was done before every page was requested
+		err = ctxt.Err()
+	}
+	data := []interface{}{}
+	for _, page := range pages[:failed] {
+		data = append(data, page...)
+	}
+	return data, apierr, err
+}
@@ -49,6 +49,11 @@ func (e *PerformancePolicy) Create(ro *PerformancePolicyCreateRequest) (*Perform
 	return resp, nil, nil
 }
 
+func (e *PerformancePolicy) CreateE(ro *PerformancePolicyCreateRequest) (*PerformancePolicy, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type PerformancePolicyListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -77,6 +82,11 @@ func (e *PerformancePolicy) List(ro *PerformancePolicyListRequest) ([]*Performan
 	return resp, nil, nil
 }
 
+func (e *PerformancePolicy) ListE(ro *PerformancePolicyListRequest) ([]*PerformancePolicy, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type PerformancePolicyGetRequest struct {
 	Ctxt context.Context `json:"-"`
 }
@@ -97,6 +107,11 @@ func (e *PerformancePolicy) Get(ro *PerformancePolicyGetRequest) (*PerformancePo
 	return resp, nil, nil
 }
 
+func (e *PerformancePolicy) GetE(ro *PerformancePolicyGetRequest) (*PerformancePolicy, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type PerformancePolicySetRequest struct {
 	Ctxt              context.Context `json:"-"`
 	WriteIopsMax      int             `json:"write_iops_max" mapstructure:"write_iops_max"`
@@ -124,6 +139,11 @@ func (e *PerformancePolicy) Set(ro *PerformancePolicySetRequest) (*PerformancePo
 
 }
 
+func (e *PerformancePolicy) SetE(ro *PerformancePolicySetRequest) (*PerformancePolicy, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type PerformancePolicyDeleteRequest struct {
 	Ctxt context.Context `json:"-"`
 }
@@ -142,3 +162,8 @@ func (e *PerformancePolicy) Delete(ro *PerformancePolicyDeleteRequest) (*Perform
 	}
 	return resp, nil, nil
 }
+
+func (e *PerformancePolicy) DeleteE(ro *PerformancePolicyDeleteRequest) (*PerformancePolicy, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
@@ -64,6 +64,9 @@ func newPlacementPolicies(path string) *PlacementPolicies {
 }
 
 func (e *PlacementPolicies) Create(ro *PlacementPoliciesCreateRequest) (*PlacementPolicy, *ApiErrorResponse, error) {
+	if err := requireApiVersion(ro.Ctxt, placementPolicyApiVersion, "placement_policies"); err != nil {
+		return nil, nil, err
+	}
 	gro := &greq.RequestOptions{JSON: ro}
 	rs, apierr, err := GetConn(ro.Ctxt).Post(ro.Ctxt, e.Path, gro)
 	if apierr != nil {
@@ -79,12 +82,20 @@ func (e *PlacementPolicies) Create(ro *PlacementPoliciesCreateRequest) (*Placeme
 	return resp, nil, nil
 }
 
+func (e *PlacementPolicies) CreateE(ro *PlacementPoliciesCreateRequest) (*PlacementPolicy, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type PlacementPoliciesListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
 }
 
 func (e *PlacementPolicies) List(ro *PlacementPoliciesListRequest) ([]*PlacementPolicy, *ApiErrorResponse, error) {
+	if err := requireApiVersion(ro.Ctxt, placementPolicyApiVersion, "placement_policies"); err != nil {
+		return nil, nil, err
+	}
 	gro := &greq.RequestOptions{
 		JSON:   ro,
 		Params: ro.Params.ToMap()}
@@ -107,12 +118,20 @@ func (e *PlacementPolicies) List(ro *PlacementPoliciesListRequest) ([]*Placement
 	return resp, nil, nil
 }
 
+func (e *PlacementPolicies) ListE(ro *PlacementPoliciesListRequest) ([]*PlacementPolicy, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type PlacementPoliciesGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Name string          `json:"name" mapstructure:"name"`
 }
 
 func (e *PlacementPolicies) Get(ro *PlacementPoliciesGetRequest) (*PlacementPolicy, *ApiErrorResponse, error) {
+	if err := requireApiVersion(ro.Ctxt, placementPolicyApiVersion, "placement_policies"); err != nil {
+		return nil, nil, err
+	}
 	gro := &greq.RequestOptions{JSON: ro}
 	rs, apierr, err := GetConn(ro.Ctxt).Get(ro.Ctxt, _path.Join(e.Path, ro.Name), gro)
 	if apierr != nil {
@@ -128,6 +147,11 @@ func (e *PlacementPolicies) Get(ro *PlacementPoliciesGetRequest) (*PlacementPoli
 	return resp, nil, nil
 }
 
+func (e *PlacementPolicies) GetE(ro *PlacementPoliciesGetRequest) (*PlacementPolicy, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type PlacementPolicySetRequest struct {
 	Ctxt  context.Context `json:"-"`
 	Name  string          `json:"name,omitempty" mapstructure:"name"`
@@ -137,6 +161,9 @@ type PlacementPolicySetRequest struct {
 }
 
 func (e *PlacementPolicy) Set(ro *PlacementPolicySetRequest) (*PlacementPolicy, *ApiErrorResponse, error) {
+	if err := requireApiVersion(ro.Ctxt, placementPolicyApiVersion, "placement_policies"); err != nil {
+		return nil, nil, err
+	}
 	gro := &greq.RequestOptions{JSON: ro}
 	rs, apierr, err := GetConn(ro.Ctxt).Put(ro.Ctxt, e.Path, gro)
 	if apierr != nil {
@@ -152,11 +179,19 @@ func (e *PlacementPolicy) Set(ro *PlacementPolicySetRequest) (*PlacementPolicy,
 	return resp, nil, nil
 }
 
+func (e *PlacementPolicy) SetE(ro *PlacementPolicySetRequest) (*PlacementPolicy, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type PlacementPolicyDeleteRequest struct {
 	Ctxt context.Context `json:"-"`
 }
 
 func (e *PlacementPolicy) Delete(ro *PlacementPolicyDeleteRequest) (*PlacementPolicy, *ApiErrorResponse, error) {
+	if err := requireApiVersion(ro.Ctxt, placementPolicyApiVersion, "placement_policies"); err != nil {
+		return nil, nil, err
+	}
 	rs, apierr, err := GetConn(ro.Ctxt).Delete(ro.Ctxt, e.Path, nil)
 	if apierr != nil {
 		return nil, apierr, err
@@ -171,11 +206,19 @@ func (e *PlacementPolicy) Delete(ro *PlacementPolicyDeleteRequest) (*PlacementPo
 	return resp, nil, nil
 }
 
+func (e *PlacementPolicy) DeleteE(ro *PlacementPolicyDeleteRequest) (*PlacementPolicy, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
+
 type PlacementPolicyReloadRequest struct {
 	Ctxt context.Context `json:"-"`
 }
 
 func (e *PlacementPolicy) Reload(ro *PlacementPolicyReloadRequest) (*PlacementPolicy, *ApiErrorResponse, error) {
+	if err := requireApiVersion(ro.Ctxt, placementPolicyApiVersion, "placement_policies"); err != nil {
+		return nil, nil, err
+	}
 	gro := &greq.RequestOptions{JSON: ro}
 	rs, apierr, err := GetConn(ro.Ctxt).Get(ro.Ctxt, e.Path, gro)
 	if apierr != nil {
@@ -190,3 +233,8 @@ func (e *PlacementPolicy) Reload(ro *PlacementPolicyReloadRequest) (*PlacementPo
 	}
 	return resp, nil, nil
 }
+
+func (e *PlacementPolicy) ReloadE(ro *PlacementPolicyReloadRequest) (*PlacementPolicy, error) {
+	resp, apierr, err := e.Reload(ro)
+	return resp, AsError(apierr, err)
+}
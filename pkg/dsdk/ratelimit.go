@@ -0,0 +1,160 @@
+package dsdk
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+)
+
+// tokenBucket allows rate events per second on average with bursts of up to
+// burst events
+type tokenBucket struct {
+	m      sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until a token is available or ctxt is done
+func (b *tokenBucket) wait(ctxt context.Context) error {
+	b.m.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	// take the token right away, going negative reserves one that will be
+	// refilled by the time we're done waiting
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.m.Unlock()
+
+	if err := sleepCtx(ctxt, delay); err != nil {
+		// give the reservation back for someone else to use
+		b.refund()
+		return err
+	}
+	return nil
+}
+
+// refund gives back a token taken by wait for a request that wasn't made
+func (b *tokenBucket) refund() {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// requestLimiter throttles the requests made by an ApiConnection
+type requestLimiter struct {
+	m        sync.RWMutex
+	global   *tokenBucket
+	routes   map[string]*tokenBucket
+	inFlight chan struct{}
+}
+
+func newRequestLimiter() *requestLimiter {
+	return &requestLimiter{routes: map[string]*tokenBucket{}}
+}
+
+// acquire waits until a request to route may be made.  The returned func must
+// be called once the request is done
+func (l *requestLimiter) acquire(ctxt context.Context, route string) (func(), error) {
+	l.m.RLock()
+	global, perRoute, inFlight := l.global, l.routes[route], l.inFlight
+	l.m.RUnlock()
+
+	if perRoute != nil {
+		if err := perRoute.wait(ctxt); err != nil {
+			return nil, err
+		}
+	}
+	if global != nil {
+		if err := global.wait(ctxt); err != nil {
+			// the request won't be made, give the route token back
+			if perRoute != nil {
+				perRoute.refund()
+			}
+			return nil, err
+		}
+	}
+	if inFlight == nil {
+		return func() {}, nil
+	}
+	select {
+	case inFlight <- struct{}{}:
+		return func() { <-inFlight }, nil
+	case <-ctxt.Done():
+		if perRoute != nil {
+			perRoute.refund()
+		}
+		if global != nil {
+			global.refund()
+		}
+		return nil, ctxt.Err()
+	}
+}
+
+// SetRateLimit limits the connection to rps requests per second on average
+// with bursts of up to burst requests.  A rps of 0 removes the limit
+func (c *ApiConnection) SetRateLimit(rps float64, burst int) {
+	c.limiter.m.Lock()
+	defer c.limiter.m.Unlock()
+	c.limiter.global = nil
+	if rps > 0 {
+		c.limiter.global = newTokenBucket(rps, burst)
+	}
+}
+
+// SetRouteRateLimit limits requests to a single route, in addition to the
+// limit set by SetRateLimit.  Routes are matched after canonicalization so
+// "app_instances/:id" and "app_instances/my-ai" are the same route.  A rps of
+// 0 removes the limit
+func (c *ApiConnection) SetRouteRateLimit(route string, rps float64, burst int) {
+	key := c.canonicalRoute(route)
+	c.limiter.m.Lock()
+	defer c.limiter.m.Unlock()
+	delete(c.limiter.routes, key)
+	if rps > 0 {
+		c.limiter.routes[key] = newTokenBucket(rps, burst)
+	}
+}
+
+// SetMaxInFlight caps the number of requests the connection has outstanding at
+// any time.  It should be called before the connection is shared between
+// goroutines.  A max of 0 removes the cap
+func (c *ApiConnection) SetMaxInFlight(max int) {
+	c.limiter.m.Lock()
+	defer c.limiter.m.Unlock()
+	c.limiter.inFlight = nil
+	if max > 0 {
+		c.limiter.inFlight = make(chan struct{}, max)
+	}
+}
+
+// canonicalRoute returns the canonical form of a route relative to the api
+// version, eg. "app_instances/my-ai" becomes "/v2.2/app_instances/:id"
+func (c *ApiConnection) canonicalRoute(route string) string {
+	v := c.ApiVersion()
+	return canonicalizeRoute(path.Join("/v"+v, route), v)
+}
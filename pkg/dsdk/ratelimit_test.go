@@ -0,0 +1,89 @@
+package dsdk
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(20, 1)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := b.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first token is free, the next two take 50ms each
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("3 tokens at 20/s with a burst of 1 took %s", d)
+	}
+
+	ctxt, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	slow := newTokenBucket(0.1, 1)
+	_ = slow.wait(ctxt)
+	if err := slow.wait(ctxt); err != context.DeadlineExceeded {
+		t.Errorf("expected the wait to be cancelled, got %v", err)
+	}
+}
+
+func TestRequestLimiterInFlight(t *testing.T) {
+	l := newRequestLimiter()
+	l.inFlight = make(chan struct{}, 1)
+	release, err := l.acquire(context.Background(), "/v2.2/system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctxt, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctxt, "/v2.2/system"); err != context.DeadlineExceeded {
+		t.Errorf("expected the second request to wait for the first, got %v", err)
+	}
+	release()
+	release, err = l.acquire(context.Background(), "/v2.2/system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
+
+func TestRequestLimiterRefund(t *testing.T) {
+	l := newRequestLimiter()
+	l.routes["/v2.2/system"] = newTokenBucket(0.1, 1)
+	l.global = newTokenBucket(0.1, 1)
+	// empty the global bucket so the next request has to wait for it
+	if err := l.global.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctxt, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctxt, "/v2.2/system"); err != context.DeadlineExceeded {
+		t.Fatalf("expected the request to wait for the global limit, got %v", err)
+	}
+	// the route token of the cancelled request is available again
+	ctxt, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.routes["/v2.2/system"].wait(ctxt); err != nil {
+		t.Errorf("expected the route token to be refunded, got %v", err)
+	}
+
+	l = newRequestLimiter()
+	l.routes["/v2.2/system"] = newTokenBucket(0.1, 1)
+	l.global = newTokenBucket(0.1, 1)
+	l.inFlight = make(chan struct{}, 1)
+	l.inFlight <- struct{}{}
+	ctxt, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctxt, "/v2.2/system"); err != context.DeadlineExceeded {
+		t.Fatalf("expected the request to wait for the in flight cap, got %v", err)
+	}
+	<-l.inFlight
+	ctxt, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	release, err := l.acquire(ctxt, "/v2.2/system")
+	if err != nil {
+		t.Fatalf("expected the tokens to be refunded, got %v", err)
+	}
+	release()
+}
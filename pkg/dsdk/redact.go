@@ -0,0 +1,147 @@
+package dsdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+const redactedValue = "********"
+
+// DefaultRedactedFields are the JSON fields whose values are masked whenever
+// a request or response payload is logged.  Fields are matched case
+// insensitively and may contain shell patterns, eg. "*_pswd"
+var DefaultRedactedFields = []string{"*_pswd", "secret_key", "private_key", "access_key", "password"}
+
+// redactedHeaders are never logged
+var redactedHeaders = []string{"Auth-Token", "Authorization"}
+
+type redactor struct {
+	m      sync.RWMutex
+	fields []string
+}
+
+func newRedactor() *redactor {
+	r := &redactor{}
+	r.add(DefaultRedactedFields...)
+	return r
+}
+
+func (r *redactor) add(fields ...string) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	for _, f := range fields {
+		r.fields = append(r.fields, strings.ToLower(f))
+	}
+}
+
+func (r *redactor) matches(field string) bool {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	field = strings.ToLower(field)
+	for _, f := range r.fields {
+		if ok, _ := path.Match(f, field); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redact returns data with the values of sensitive fields masked at any
+// depth.  Payloads that aren't JSON are returned as is
+func (r *redactor) redact(data []byte) string {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// keep numbers exactly as they were sent
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return string(data)
+	}
+	if !r.walk(v) {
+		return string(data)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return redactedValue
+	}
+	return string(b)
+}
+
+// walk masks sensitive fields in place and reports whether any were found
+func (r *redactor) walk(v interface{}) bool {
+	found := false
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if val != nil && val != "" && r.matches(k) {
+				t[k] = redactedValue
+				found = true
+			} else if r.walk(val) {
+				found = true
+			}
+		}
+	case []interface{}:
+		for _, val := range t {
+			if r.walk(val) {
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+// redactHeaders returns a copy of h without credentials
+func redactHeaders(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range redactedHeaders {
+		if h.Get(k) != "" {
+			h.Set(k, redactedValue)
+		}
+	}
+	return h
+}
+
+// AddRedactedFields masks the values of fields, in addition to
+// DefaultRedactedFields, whenever payloads are logged.  Fields may contain
+// shell patterns, eg. "*_token"
+func (c *ApiConnection) AddRedactedFields(fields ...string) {
+	c.redactor.add(fields...)
+}
+
+// RedactPayload returns the JSON payload data with the values of
+// DefaultRedactedFields and fields masked, eg. before storing it outside of
+// the process.  Payloads that aren't JSON are returned as is
+func RedactPayload(data []byte, fields ...string) []byte {
+	r := newRedactor()
+	r.add(fields...)
+	return []byte(r.redact(data))
+}
+
+// RedactForm is RedactPayload for url encoded form payloads, like the one sent
+// to log in
+func RedactForm(data []byte, fields ...string) []byte {
+	r := newRedactor()
+	r.add(fields...)
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return data
+	}
+	for k, vs := range values {
+		if r.matches(k) {
+			for i := range vs {
+				vs[i] = redactedValue
+			}
+		}
+	}
+	return []byte(values.Encode())
+}
+
+// RedactHeaders returns a copy of h with the values of the headers carrying
+// credentials masked
+func RedactHeaders(h http.Header) http.Header {
+	return redactHeaders(h)
+}
@@ -0,0 +1,43 @@
+package dsdk
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	r := newRedactor()
+	r.add("Token")
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{
+			`{"name": "my-ai", "descr": "has a secret in it"}`,
+			`{"name": "my-ai", "descr": "has a secret in it"}`,
+		},
+		{
+			`{"auth": {"type": "chap", "initiator_user_name": "bob", "initiator_pswd": "hunter2", "target_pswd": ""}}`,
+			`{"auth":{"initiator_pswd":"********","initiator_user_name":"bob","target_pswd":"","type":"chap"}}`,
+		},
+		{
+			`{"data": [{"access_key": "AKIA", "Secret_Key": "abc", "private_key": "-----BEGIN", "size": 12345678901234567890}], "token": "t"}`,
+			`{"data":[{"Secret_Key":"********","access_key":"********","private_key":"********","size":12345678901234567890}],"token":"********"}`,
+		},
+	} {
+		if got := r.redact([]byte(tc.in)); got != tc.want {
+			t.Errorf("redact(%s) = %s, want %s", tc.in, got, tc.want)
+		}
+	}
+
+	h := http.Header{}
+	h.Set("Auth-Token", "thekey")
+	h.Set("Tenant", "/root")
+	rh := redactHeaders(h)
+	if rh.Get("Auth-Token") != redactedValue || rh.Get("Tenant") != "/root" || h.Get("Auth-Token") != "thekey" {
+		t.Errorf("unexpected headers %v from %v", rh, h)
+	}
+
+	if got := string(RedactForm([]byte("name=admin&password=hunter2&remote_server=ldap"), "remote_*")); got != "name=admin&password=%2A%2A%2A%2A%2A%2A%2A%2A&remote_server=%2A%2A%2A%2A%2A%2A%2A%2A" {
+		t.Errorf("unexpected form %s", got)
+	}
+}
@@ -79,6 +79,11 @@ func (e *RemoteProviders) Create(ro *RemoteProvidersCreateRequest) (*RemoteProvi
 	return resp, nil, nil
 }
 
+func (e *RemoteProviders) CreateE(ro *RemoteProvidersCreateRequest) (*RemoteProvider, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type RemoteProvidersListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -108,6 +113,11 @@ func (e *RemoteProviders) List(ro *RemoteProvidersListRequest) ([]*RemoteProvide
 	return resp, nil, nil
 }
 
+func (e *RemoteProviders) ListE(ro *RemoteProvidersListRequest) ([]*RemoteProvider, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type RemoteProvidersGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Id   string          `json:"-"`
@@ -130,6 +140,11 @@ func (e *RemoteProviders) Get(ro *RemoteProvidersGetRequest) (*RemoteProvider, *
 	return resp, nil, nil
 }
 
+func (e *RemoteProviders) GetE(ro *RemoteProvidersGetRequest) (*RemoteProvider, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type RemoteProvidersRefreshRequest struct {
 	Ctxt context.Context `json:"-"`
 	Uuid string          `json:"-"`
@@ -158,6 +173,11 @@ func (e *RemoteProviders) Refresh(ro *RemoteProvidersRefreshRequest) (*RemotePro
 	return resp, nil, nil
 }
 
+func (e *RemoteProviders) RefreshE(ro *RemoteProvidersRefreshRequest) (*RemoteProvidersRefreshResponse, error) {
+	resp, apierr, err := e.Refresh(ro)
+	return resp, AsError(apierr, err)
+}
+
 type RemoteProviderSetRequest struct {
 	Ctxt        context.Context `json:"-"`
 	ProjectName string          `json:"project_name,omitempty" mapstructure:"project_name"`
@@ -187,6 +207,11 @@ func (e *RemoteProvider) Set(ro *RemoteProviderSetRequest) (*RemoteProvider, *Ap
 	return resp, nil, nil
 }
 
+func (e *RemoteProvider) SetE(ro *RemoteProviderSetRequest) (*RemoteProvider, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type RemoteProviderDeleteRequest struct {
 	Ctxt  context.Context `json:"-"`
 	Force bool            `json:"force,omitempty" mapstructure:"force"`
@@ -194,7 +219,7 @@ type RemoteProviderDeleteRequest struct {
 
 func (e *RemoteProvider) Delete(ro *RemoteProviderDeleteRequest) (*RemoteProvider, *ApiErrorResponse, error) {
 	if ro == nil {
-		return nil, nil, badStatus[InvalidRequest]
+		return nil, nil, ErrValidation
 	}
 	v := reflect.ValueOf(*ro)
 	t := reflect.TypeOf(*ro)
@@ -217,6 +242,11 @@ func (e *RemoteProvider) Delete(ro *RemoteProviderDeleteRequest) (*RemoteProvide
 	return resp, nil, nil
 }
 
+func (e *RemoteProvider) DeleteE(ro *RemoteProviderDeleteRequest) (*RemoteProvider, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
+
 type RemoteProviderAppTemplate struct {
 	Path           string `json:"path,omitempty" mapstructure:"path"`
 	ResolvedPath   string `json:"resolved_path,omitempty" mapstructure:"resolved_path"`
@@ -244,6 +274,11 @@ func (e *RemoteProvider) Reload(ro *RemoteProviderReloadRequest) (*RemoteProvide
 	return resp, nil, nil
 }
 
+func (e *RemoteProvider) ReloadE(ro *RemoteProviderReloadRequest) (*RemoteProvider, error) {
+	resp, apierr, err := e.Reload(ro)
+	return resp, AsError(apierr, err)
+}
+
 type RemoteProviderOperationsSetRequest struct {
 	Ctxt        context.Context `json:"-"`
 	OperationId string          `json:"-"`
@@ -282,3 +317,8 @@ func (e *RemoteProvider) SetOperation(ao *RemoteProviderOperationsSetRequest) (*
 
 	return resp, nil, nil
 }
+
+func (e *RemoteProvider) SetOperationE(ao *RemoteProviderOperationsSetRequest) (*RemoteOperation, error) {
+	resp, apierr, err := e.SetOperation(ao)
+	return resp, AsError(apierr, err)
+}
@@ -0,0 +1,210 @@
+package dsdk
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryPolicy decides whether a failed request should be attempted again and
+// how long to wait before doing so.  A policy can be set for every request on
+// an ApiConnection via SetRetryPolicy or for a single request via
+// WithRetryPolicy.
+type RetryPolicy interface {
+	// ShouldRetry is called after every failed attempt.  attempt is the number
+	// of attempts made so far, starting at 1
+	ShouldRetry(attempt int, apiresp *ApiErrorResponse, err error) bool
+	// Backoff returns how long to wait after the given failed attempt before
+	// trying again.  retryAfter is the delay requested by the server via the
+	// Retry-After header, if any
+	Backoff(attempt int, retryAfter time.Duration) time.Duration
+	// Timeout is the overall time budget for all retries of a request
+	Timeout() time.Duration
+}
+
+var (
+	// DefaultRetryPolicy retries 503s and connection errors with a quadratic
+	// backoff until RetryTimeout is reached
+	DefaultRetryPolicy RetryPolicy = defaultRetryPolicy{}
+	// NoRetryPolicy never retries a request
+	NoRetryPolicy RetryPolicy = noRetryPolicy{}
+)
+
+// WithRetryPolicy returns a context that causes requests made with it to use
+// the provided RetryPolicy instead of the one configured on the ApiConnection
+func WithRetryPolicy(ctxt context.Context, p RetryPolicy) context.Context {
+	return context.WithValue(ctxt, retryPolicyCtxKey, p)
+}
+
+func retryPolicyFrom(ctxt context.Context) (RetryPolicy, bool) {
+	p, ok := ctxt.Value(retryPolicyCtxKey).(RetryPolicy)
+	return p, ok && p != nil
+}
+
+// isRetryableError reports whether err is a transport level error that is
+// worth retrying
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, ErrConnection) || strings.Contains(err.Error(), "connect: connection refused")
+}
+
+type defaultRetryPolicy struct{}
+
+func (p defaultRetryPolicy) ShouldRetry(attempt int, apiresp *ApiErrorResponse, err error) bool {
+	if apiresp != nil {
+		return apiresp.Http == Retry503
+	}
+	return isRetryableError(err)
+}
+
+func (p defaultRetryPolicy) Backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	// the first retry is immediate, subsequent ones wait 1s, 4s, 9s, ...
+	n := attempt - 1
+	return time.Second * time.Duration(n*n)
+}
+
+func (p defaultRetryPolicy) Timeout() time.Duration {
+	return time.Second * time.Duration(RetryTimeout)
+}
+
+type noRetryPolicy struct{}
+
+func (p noRetryPolicy) ShouldRetry(attempt int, apiresp *ApiErrorResponse, err error) bool {
+	return false
+}
+
+func (p noRetryPolicy) Backoff(attempt int, retryAfter time.Duration) time.Duration {
+	return 0
+}
+
+func (p noRetryPolicy) Timeout() time.Duration {
+	return 0
+}
+
+// ExponentialBackoffPolicy is a configurable RetryPolicy whose delay grows by
+// Multiplier after every attempt, capped at MaxInterval and randomized by
+// Jitter so that many concurrent callers don't retry in lockstep
+type ExponentialBackoffPolicy struct {
+	// Statuses are the HTTP status codes that will be retried.  Defaults to 503
+	Statuses []int
+	// RetryConnectionErrors enables retrying requests that failed to connect
+	RetryConnectionErrors bool
+	// InitialInterval is the delay before the first retry.  Defaults to 1s
+	InitialInterval time.Duration
+	// MaxInterval caps the delay between two attempts.  Zero means no cap
+	MaxInterval time.Duration
+	// Multiplier is applied to the delay after every attempt.  Defaults to 2
+	Multiplier float64
+	// Jitter randomizes each delay by up to +/- this fraction (0.0 - 1.0)
+	Jitter float64
+	// MaxElapsed is the overall retry deadline.  Defaults to RetryTimeout
+	MaxElapsed time.Duration
+	// IgnoreRetryAfter disables honoring the server's Retry-After header
+	IgnoreRetryAfter bool
+
+	m   sync.Mutex
+	rnd *rand.Rand
+}
+
+func (p *ExponentialBackoffPolicy) ShouldRetry(attempt int, apiresp *ApiErrorResponse, err error) bool {
+	if apiresp != nil {
+		if len(p.Statuses) == 0 {
+			return apiresp.Http == Retry503
+		}
+		for _, s := range p.Statuses {
+			if apiresp.Http == s {
+				return true
+			}
+		}
+		return false
+	}
+	return p.RetryConnectionErrors && isRetryableError(err)
+}
+
+func (p *ExponentialBackoffPolicy) Backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 && !p.IgnoreRetryAfter {
+		return retryAfter
+	}
+	initial := p.InitialInterval
+	if initial <= 0 {
+		initial = time.Second
+	}
+	mult := p.Multiplier
+	if mult <= 0 {
+		mult = 2
+	}
+	d := float64(initial) * math.Pow(mult, float64(attempt-1))
+	if p.MaxInterval > 0 && d > float64(p.MaxInterval) {
+		d = float64(p.MaxInterval)
+	}
+	if p.Jitter > 0 {
+		p.m.Lock()
+		if p.rnd == nil {
+			p.rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+		}
+		r := p.rnd.Float64()
+		p.m.Unlock()
+		d += d * p.Jitter * (2*r - 1)
+	}
+	if d < 0 {
+		d = 0
+	}
+	return time.Duration(d)
+}
+
+func (p *ExponentialBackoffPolicy) Timeout() time.Duration {
+	if p.MaxElapsed > 0 {
+		return p.MaxElapsed
+	}
+	return time.Second * time.Duration(RetryTimeout)
+}
+
+// sleepCtx waits for d or until ctxt is done, whichever comes first
+func sleepCtx(ctxt context.Context, d time.Duration) error {
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctxt.Done():
+		return ctxt.Err()
+	}
+}
+
+// parseRetryAfter understands both forms of the Retry-After header, a number
+// of seconds or an HTTP date
+func parseRetryAfter(h http.Header) time.Duration {
+	v := strings.TrimSpace(h.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
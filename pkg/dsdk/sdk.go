@@ -4,13 +4,14 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	udc "github.com/Datera/go-udc/pkg/udc"
 	uuid "github.com/google/uuid"
 )
 
 const (
-	VERSION         = "1.1.5"
+	VERSION         = "1.5.0"
 	VERSION_HISTORY = `
 		1.1.0 -- Revamped SDK to new directory structure, switched to using grequests and added UDC support
 		1.1.1 -- Added LDAP server support
@@ -18,11 +19,17 @@ const (
 		1.1.3 -- Support for Go modules
 		1.1.4 -- AppInstance AppTemplate datastructure bugfix
 		1.1.5 -- HTTP 503 Retry and Connection Retry support
+		1.2.0 -- TLS certificates are verified by default, see TLSConfig for CA bundles, client certificates, pinning and insecure mode
+		1.3.0 -- API version negotiation when the UDC ApiVersion is empty or "auto"
+		1.4.0 -- Typed context keys, use WithConnection, WithTraceID and WithQuiet instead of the "conn", "tid" and "quiet" keys
+		1.5.0 -- Logging goes through the Logger of each connection, the global logrus logger is no longer configured on import
 	`
 )
 
 type SDK struct {
-	conf                 *udc.UDC
+	conf *udc.UDC
+	// tenant overrides the tenant of the connection when set by ForTenant
+	tenant               string
 	Conn                 *ApiConnection
 	Ctxt                 context.Context
 	AccessNetworkIpPools *AccessNetworkIpPools
@@ -52,11 +59,38 @@ func NewSDKWithHTTPClient(c *udc.UDC, secure bool, client *http.Client) (*SDK, e
 	if c == nil {
 		c, err = udc.GetConfig()
 		if err != nil {
-			Log().Error(err)
+			defaultLog().Errorf("%s", err)
 			return nil, err
 		}
 	}
-	conn := NewApiConnectionWithHTTPClient(c, secure, client)
+	conn, err := NewApiConnectionWithHTTPClientE(c, secure, client)
+	if err != nil {
+		defaultLog().Errorf("%s", err)
+		return nil, err
+	}
+	if c.ApiVersion == "" || c.ApiVersion == ApiVersionAuto {
+		ctxt, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if _, err = conn.NegotiateApiVersion(ctxt); err != nil {
+			conn.log(ctxt).Errorf("%s", err)
+			return nil, err
+		}
+	}
+	return newSDK(c, conn), nil
+}
+
+// NewSDKWithTLS returns an SDK whose connection verifies the cluster's
+// certificate according to t
+func NewSDKWithTLS(c *udc.UDC, secure bool, t *TLSConfig) (*SDK, error) {
+	client, err := newTLSClient(t)
+	if err != nil {
+		defaultLog().Errorf("%s", err)
+		return nil, err
+	}
+	return NewSDKWithHTTPClient(c, secure, client)
+}
+
+func newSDK(c *udc.UDC, conn *ApiConnection) *SDK {
 	return &SDK{
 		conf:                 c,
 		Conn:                 conn,
@@ -76,7 +110,7 @@ func NewSDKWithHTTPClient(c *udc.UDC, secure bool, client *http.Client) (*SDK, e
 		SystemEvents:         newSystemEvents("/"),
 		Tenants:              newTenants("/"),
 		UserData:             newUserDatas("/"),
-	}, nil
+	}
 }
 
 func (c SDK) SetDriver(d string) {
@@ -84,18 +118,43 @@ func (c SDK) SetDriver(d string) {
 }
 
 func (c SDK) WithContext(ctxt context.Context) context.Context {
-	return context.WithValue(ctxt, "conn", c.Conn)
+	ctxt = WithConnection(ctxt, c.Conn)
+	if c.tenant != "" {
+		ctxt = WithTenant(ctxt, c.tenant)
+	}
+	return ctxt
 }
 
 func (c SDK) NewContext() context.Context {
-	ctxt := context.WithValue(context.Background(), "conn", c.Conn)
-	ctxt = context.WithValue(ctxt, "tid", uuid.Must(uuid.NewRandom()).String())
+	ctxt := WithConnection(context.Background(), c.Conn)
+	ctxt = WithTraceID(ctxt, uuid.Must(uuid.NewRandom()).String())
+	if c.tenant != "" {
+		ctxt = WithTenant(ctxt, c.tenant)
+	}
 	return ctxt
 }
 
+// ForTenant returns a view of the SDK whose contexts make requests within
+// tenant, eg. "/root/foo".  The view shares the connection, and its session,
+// with the SDK it was created from
+func (c SDK) ForTenant(tenant string) *SDK {
+	c.tenant = tenant
+	return &c
+}
+
+// ApiVersion returns the API version used to talk to the cluster
+func (c SDK) ApiVersion() string {
+	return c.Conn.ApiVersion()
+}
+
+// SetLogger makes the SDK log through l instead of the default Logger
+func (c SDK) SetLogger(l Logger) {
+	c.Conn.SetLogger(l)
+}
+
 func (c SDK) GetDateraVersion() (string, error) {
 	sys, apierr, err := c.System.Get(&SystemGetRequest{
-		Ctxt: context.WithValue(c.NewContext(), "quiet", true),
+		Ctxt: WithQuiet(c.NewContext()),
 	})
 	if err != nil {
 		return "", err
@@ -109,8 +168,9 @@ func (c SDK) GetDateraVersion() (string, error) {
 // Cleans AppInstances, AppTemplates, StorageInstances, Initiators and InitiatorGroups under
 // the currently configured tenant
 func (c SDK) HealthCheck() error {
+	ctxt := WithQuiet(c.NewContext())
 	sns, apierr, err := c.StorageNodes.List(&StorageNodesListRequest{
-		Ctxt: context.WithValue(c.NewContext(), "quiet", true),
+		Ctxt: ctxt,
 	})
 	if err != nil {
 		return err
@@ -118,9 +178,13 @@ func (c SDK) HealthCheck() error {
 	if apierr != nil {
 		return fmt.Errorf("ApiError: %s", Pretty(apierr))
 	}
-	Log().Debugf("Connected to cluster: %s with tenant %s.", c.conf.MgmtIp, c.conf.Tenant)
+	tenant := c.conf.Tenant
+	if c.tenant != "" {
+		tenant = c.tenant
+	}
+	c.Conn.log(ctxt).Debugf("Connected to cluster: %s with tenant %s.", c.conf.MgmtIp, tenant)
 	for _, sn := range sns {
-		Log().Debugf("Found Storage Node: %s", sn.Uuid)
+		c.Conn.log(ctxt).Debugf("Found Storage Node: %s", sn.Uuid)
 	}
 	return nil
 }
@@ -0,0 +1,216 @@
+package dsdk
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	greq "github.com/levigross/grequests"
+)
+
+// DefaultSessionRefreshBefore is how long before a session expires it is
+// refreshed in the background
+var DefaultSessionRefreshBefore = time.Minute
+
+// SessionConfig describes how long the sessions of a connection last
+type SessionConfig struct {
+	// TTL is how long a session lasts after logging in.  When 0 sessions are
+	// only refreshed once a request fails with a 401
+	TTL time.Duration
+	// RefreshBefore is how long before expiring a session that was used since
+	// the last login is refreshed in the background, defaults to
+	// DefaultSessionRefreshBefore
+	RefreshBefore time.Duration
+	// NoBackgroundRefresh disables background refreshes, an expired session is
+	// then refreshed by the next request
+	NoBackgroundRefresh bool
+}
+
+// SetSessionConfig enables tracking when sessions expire so they are
+// refreshed before requests start failing.  It should be called before the
+// connection is shared between goroutines
+func (c *ApiConnection) SetSessionConfig(conf SessionConfig) {
+	c.sessionConf = conf
+}
+
+// SessionExpires returns when the current session is expected to expire, zero
+// if it is unknown or there is no session
+func (c *ApiConnection) SessionExpires() time.Time {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	return c.sessionExpires
+}
+
+// sessionCall is a login shared by every caller that needs one at the same time
+type sessionCall struct {
+	done   chan struct{}
+	apierr *ApiErrorResponse
+	err    error
+}
+
+// refreshSession logs in unless the connection already has a session other
+// than stale.  Concurrent callers share a single login, and are able to give
+// up waiting on it when their own context is done.  A failed background
+// refresh keeps the current session
+func (c *ApiConnection) refreshSession(ctxt context.Context, stale string, background bool) (*ApiErrorResponse, error) {
+	for {
+		c.m.Lock()
+		if c.apikey != "" && c.apikey != stale {
+			// any time the connection has an apikey we can skip the login because
+			// the apikey gets cleared after a session expiration before attempting to login
+			// therefore a non-empty apikey can be assumed to be valid
+			c.m.Unlock()
+			return nil, nil
+		}
+		call := c.loginCall
+		if call == nil {
+			call = &sessionCall{done: make(chan struct{})}
+			c.loginCall = call
+			c.m.Unlock()
+
+			call.apierr, call.err = c.login(ctxt, stale, background)
+			c.m.Lock()
+			c.loginCall = nil
+			c.m.Unlock()
+			close(call.done)
+			return call.apierr, call.err
+		}
+		c.m.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctxt.Done():
+			return nil, ctxt.Err()
+		}
+		if (call.err == context.Canceled || call.err == context.DeadlineExceeded) && ctxt.Err() == nil {
+			// whoever started the login gave up on it, but we haven't
+			continue
+		}
+		return call.apierr, call.err
+	}
+}
+
+// login replaces the stale session with one from the TokenStore or a new one
+func (c *ApiConnection) login(ctxt context.Context, stale string, background bool) (*ApiErrorResponse, error) {
+	creds, err := c.credentials.Credentials(ctxt)
+	if err != nil {
+		c.log(ctxt).Errorf("Failed getting credentials: %s", err)
+		return nil, err
+	}
+	key := c.sessionKey(creds)
+	if c.tokenStore != nil {
+		// another connection may have logged in already
+		token, err := c.tokenStore.Load(ctxt, key)
+		if err != nil {
+			c.log(ctxt).Warnf("Failed loading stored apikey: %s", err)
+		} else if token != "" && token != stale {
+			// we don't know when the stored session was started, it will be
+			// refreshed once it gets a 401
+			c.setSession(token, key, time.Time{})
+			c.discoverEndpointsOnce()
+			return nil, nil
+		} else if token != "" {
+			if err = c.tokenStore.Delete(ctxt, key); err != nil {
+				c.log(ctxt).Warnf("Failed deleting stored apikey: %s", err)
+			}
+		}
+	}
+
+	login := &ApiLogin{}
+	ro := &greq.RequestOptions{
+		Data: map[string]string{
+			"name":     creds.Username,
+			"password": creds.Password,
+		},
+	}
+	if creds.Ldap != "" {
+		ro.Data["remote_server"] = creds.Ldap
+	}
+
+	issued := time.Now()
+	apiresp, err := c.do(ctxt, "PUT", "login", ro, login, canRetry, isSensitive, !allowLogin)
+	if c.metrics != nil {
+		c.metrics.IncLogins(apiresp == nil && err == nil)
+	}
+	if apiresp != nil || err != nil {
+		if !background {
+			c.m.Lock()
+			c.apikey = ""
+			c.m.Unlock()
+		}
+		return apiresp, err
+	}
+
+	// trust the cluster's idea of when the session started as long as the
+	// clocks roughly agree
+	if login.ReqTime > 0 {
+		if t := time.Unix(int64(login.ReqTime), 0); t.Before(issued) && issued.Sub(t) < time.Minute {
+			issued = t
+		}
+	}
+	c.setSession(login.Key, key, issued)
+	if c.tokenStore != nil {
+		if err := c.tokenStore.Store(ctxt, key, login.Key); err != nil {
+			c.log(ctxt).Warnf("Failed storing apikey: %s", err)
+		}
+	}
+	c.discoverEndpointsOnce()
+	return nil, nil
+}
+
+// setSession switches the connection to apikey and schedules its refresh.  A
+// zero issued time means the age of the session is unknown
+func (c *ApiConnection) setSession(apikey, key string, issued time.Time) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.apikey, c.tokenKey = apikey, key
+	c.sessionExpires = time.Time{}
+	if c.refreshTimer != nil {
+		c.refreshTimer.Stop()
+		c.refreshTimer = nil
+	}
+	ttl := c.sessionConf.TTL
+	if ttl <= 0 || issued.IsZero() {
+		return
+	}
+	c.sessionExpires = issued.Add(ttl)
+	if c.sessionConf.NoBackgroundRefresh {
+		return
+	}
+	before := c.sessionConf.RefreshBefore
+	if before <= 0 {
+		before = DefaultSessionRefreshBefore
+	}
+	if d := time.Until(c.sessionExpires) - before; d > 0 {
+		c.refreshTimer = time.AfterFunc(d, func() { c.backgroundRefresh(apikey, issued) })
+	}
+}
+
+func (c *ApiConnection) backgroundRefresh(stale string, issued time.Time) {
+	// sessions of connections that aren't used anymore are left to expire
+	if time.Unix(0, atomic.LoadInt64(&c.lastUsed)).Before(issued) {
+		return
+	}
+	ctxt, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if apierr, err := c.refreshSession(ctxt, stale, true); apierr != nil || err != nil {
+		c.log(ctxt).Warnf("Failed refreshing session in the background: %s, %s", Pretty(apierr), err)
+	}
+}
+
+// LogoutSession ends the session of the connection on the cluster as well as
+// forgetting it locally
+func (c *ApiConnection) LogoutSession(ctxt context.Context) (*ApiErrorResponse, error) {
+	c.m.RLock()
+	apikey, tenant := c.apikey, c.tenant
+	c.m.RUnlock()
+	if apikey == "" {
+		return nil, nil
+	}
+	ro := &greq.RequestOptions{
+		Headers: map[string]string{"tenant": tenant, "Auth-Token": apikey},
+	}
+	apiresp, err := c.do(ctxt, "PUT", "logout", ro, &ApiOuter{}, canRetry, !isSensitive, !allowLogin)
+	c.Logout()
+	return apiresp, err
+}
@@ -49,6 +49,11 @@ func (e *SnapshotPolicies) Create(ro *SnapshotPoliciesCreateRequest) (*SnapshotP
 	return resp, nil, nil
 }
 
+func (e *SnapshotPolicies) CreateE(ro *SnapshotPoliciesCreateRequest) (*SnapshotPolicy, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type SnapshotPoliciesListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -77,6 +82,11 @@ func (e *SnapshotPolicies) List(ro *SnapshotPoliciesListRequest) ([]*SnapshotPol
 	return resp, nil, nil
 }
 
+func (e *SnapshotPolicies) ListE(ro *SnapshotPoliciesListRequest) ([]*SnapshotPolicy, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type SnapshotPoliciesGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Name string          `json:"-"`
@@ -98,6 +108,11 @@ func (e *SnapshotPolicies) Get(ro *SnapshotPoliciesGetRequest) (*SnapshotPolicy,
 	return resp, nil, nil
 }
 
+func (e *SnapshotPolicies) GetE(ro *SnapshotPoliciesGetRequest) (*SnapshotPolicy, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type SnapshotPolicySetRequest struct {
 	Ctxt           context.Context `json:"-"`
 	Interval       string          `json:"name,omitempty" mapstructure:"name"`
@@ -122,6 +137,11 @@ func (e *SnapshotPolicy) Set(ro *SnapshotPolicySetRequest) (*SnapshotPolicy, *Ap
 
 }
 
+func (e *SnapshotPolicy) SetE(ro *SnapshotPolicySetRequest) (*SnapshotPolicy, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type SnapshotPolicyDeleteRequest struct {
 	Ctxt context.Context `json:"-"`
 	Id   string          `json:"id,omitempty" mapstructure:"id"`
@@ -141,3 +161,8 @@ func (e *SnapshotPolicy) Delete(ro *SnapshotPolicyDeleteRequest) (*SnapshotPolic
 	}
 	return resp, nil, nil
 }
+
+func (e *SnapshotPolicy) DeleteE(ro *SnapshotPolicyDeleteRequest) (*SnapshotPolicy, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
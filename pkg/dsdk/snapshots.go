@@ -60,6 +60,11 @@ func (e *Snapshots) Create(ro *SnapshotsCreateRequest) (*Snapshot, *ApiErrorResp
 	return resp, nil, nil
 }
 
+func (e *Snapshots) CreateE(ro *SnapshotsCreateRequest) (*Snapshot, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type SnapshotsListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -88,6 +93,11 @@ func (e *Snapshots) List(ro *SnapshotsListRequest) ([]*Snapshot, *ApiErrorRespon
 	return resp, nil, nil
 }
 
+func (e *Snapshots) ListE(ro *SnapshotsListRequest) ([]*Snapshot, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type SnapshotsGetRequest struct {
 	Ctxt      context.Context `json:"-"`
 	Timestamp string          `json:"-"`
@@ -109,6 +119,11 @@ func (e *Snapshots) Get(ro *SnapshotsGetRequest) (*Snapshot, *ApiErrorResponse,
 	return resp, nil, nil
 }
 
+func (e *Snapshots) GetE(ro *SnapshotsGetRequest) (*Snapshot, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type SnapshotSetRequest struct {
 	Ctxt               context.Context `json:"-"`
 	DeleteLocal        bool            `json:"delete_local" mapstructure:"delete_local"`
@@ -131,6 +146,11 @@ func (e *Snapshot) Set(ro *SnapshotSetRequest) (*Snapshot, *ApiErrorResponse, er
 	return resp, nil, nil
 }
 
+func (e *Snapshot) SetE(ro *SnapshotSetRequest) (*Snapshot, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type SnapshotDeleteRequest struct {
 	Ctxt               context.Context `json:"-"`
 	RemoteProviderUuid string          `json:"remote_provider_uuid,omitempty" mapstructure:"remote_provider_uuid"`
@@ -139,7 +159,7 @@ type SnapshotDeleteRequest struct {
 
 func (e *Snapshot) Delete(ro *SnapshotDeleteRequest) (*Snapshot, *ApiErrorResponse, error) {
 	if ro == nil {
-		return nil, nil, badStatus[InvalidRequest]
+		return nil, nil, ErrValidation
 	}
 	v := reflect.ValueOf(*ro)
 	t := reflect.TypeOf(*ro)
@@ -162,6 +182,11 @@ func (e *Snapshot) Delete(ro *SnapshotDeleteRequest) (*Snapshot, *ApiErrorRespon
 	return resp, nil, nil
 }
 
+func (e *Snapshot) DeleteE(ro *SnapshotDeleteRequest) (*Snapshot, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
+
 type SnapshotReloadRequest struct {
 	Ctxt context.Context `json:"-"`
 }
@@ -181,3 +206,8 @@ func (e *Snapshot) Reload(ro *SnapshotReloadRequest) (*Snapshot, *ApiErrorRespon
 	}
 	return resp, nil, nil
 }
+
+func (e *Snapshot) ReloadE(ro *SnapshotReloadRequest) (*Snapshot, error) {
+	resp, apierr, err := e.Reload(ro)
+	return resp, AsError(apierr, err)
+}
@@ -76,6 +76,11 @@ func (e *StorageInstances) Create(ro *StorageInstancesCreateRequest) (*StorageIn
 	return resp, nil, nil
 }
 
+func (e *StorageInstances) CreateE(ro *StorageInstancesCreateRequest) (*StorageInstance, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StorageInstancesListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -105,6 +110,11 @@ func (e *StorageInstances) List(ro *StorageInstancesListRequest) ([]*StorageInst
 	return resp, nil, nil
 }
 
+func (e *StorageInstances) ListE(ro *StorageInstancesListRequest) ([]*StorageInstance, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StorageInstancesGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Name string          `json:"-"`
@@ -127,6 +137,11 @@ func (e *StorageInstances) Get(ro *StorageInstancesGetRequest) (*StorageInstance
 	return resp, nil, nil
 }
 
+func (e *StorageInstances) GetE(ro *StorageInstancesGetRequest) (*StorageInstance, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StorageInstanceSetRequest struct {
 	Ctxt              context.Context      `json:"-"`
 	AccessControlMode string               `json:"access_control_mode,omitempty" mapstructure:"access_control_mode"`
@@ -156,6 +171,11 @@ func (e *StorageInstance) Set(ro *StorageInstanceSetRequest) (*StorageInstance,
 
 }
 
+func (e *StorageInstance) SetE(ro *StorageInstanceSetRequest) (*StorageInstance, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StorageInstanceDeleteRequest struct {
 	Ctxt  context.Context `json:"-"`
 	Force bool            `json:"force,omitempty" mapstructure:"force"`
@@ -177,6 +197,11 @@ func (e *StorageInstance) Delete(ro *StorageInstanceDeleteRequest) (*StorageInst
 	return resp, nil, nil
 }
 
+func (e *StorageInstance) DeleteE(ro *StorageInstanceDeleteRequest) (*StorageInstance, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StorageInstanceReloadRequest struct {
 	Ctxt context.Context `json:"-"`
 }
@@ -197,3 +222,8 @@ func (e *StorageInstance) Reload(ro *StorageInstanceReloadRequest) (*StorageInst
 	RegisterStorageInstanceEndpoints(resp)
 	return resp, nil, nil
 }
+
+func (e *StorageInstance) ReloadE(ro *StorageInstanceReloadRequest) (*StorageInstance, error) {
+	resp, apierr, err := e.Reload(ro)
+	return resp, AsError(apierr, err)
+}
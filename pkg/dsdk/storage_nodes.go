@@ -105,6 +105,11 @@ func (e *StorageNodes) List(ro *StorageNodesListRequest) ([]*StorageNode, *ApiEr
 	return resp, nil, nil
 }
 
+func (e *StorageNodes) ListE(ro *StorageNodesListRequest) ([]*StorageNode, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StorageNodesGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Uuid string          `json:"-"`
@@ -127,6 +132,11 @@ func (e *StorageNodes) Get(ro *StorageNodesGetRequest) (*StorageNode, *ApiErrorR
 	return resp, nil, nil
 }
 
+func (e *StorageNodes) GetE(ro *StorageNodesGetRequest) (*StorageNode, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StorageNodeSetRequest struct {
 	Ctxt        context.Context `json:"-"`
 	AdminState  string          `json:"admin_state,omitempty" mapstructure:"admin_state"`
@@ -151,6 +161,11 @@ func (e *StorageNode) Set(ro *StorageNodeSetRequest) (*StorageNode, *ApiErrorRes
 
 }
 
+func (e *StorageNode) SetE(ro *StorageNodeSetRequest) (*StorageNode, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StorageNodeReloadRequest struct {
 	Ctxt context.Context `json:"-"`
 }
@@ -171,3 +186,8 @@ func (e *StorageNode) Reload(ro *StorageNodeReloadRequest) (*StorageNode, *ApiEr
 	RegisterStorageNodeEndpoints(resp)
 	return resp, nil, nil
 }
+
+func (e *StorageNode) ReloadE(ro *StorageNodeReloadRequest) (*StorageNode, error) {
+	resp, apierr, err := e.Reload(ro)
+	return resp, AsError(apierr, err)
+}
@@ -45,6 +45,11 @@ func (e *StoragePools) Create(ro *StoragePoolsCreateRequest) (*StoragePool, *Api
 	return resp, nil, nil
 }
 
+func (e *StoragePools) CreateE(ro *StoragePoolsCreateRequest) (*StoragePool, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StoragePoolsListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -73,6 +78,11 @@ func (e *StoragePools) List(ro *StoragePoolsListRequest) ([]*StoragePool, *ApiEr
 	return resp, nil, nil
 }
 
+func (e *StoragePools) ListE(ro *StoragePoolsListRequest) ([]*StoragePool, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StoragePoolsGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Uuid string          `json:"-"`
@@ -94,6 +104,11 @@ func (e *StoragePools) Get(ro *StoragePoolsGetRequest) (*StoragePool, *ApiErrorR
 	return resp, nil, nil
 }
 
+func (e *StoragePools) GetE(ro *StoragePoolsGetRequest) (*StoragePool, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StoragePoolSetRequest struct {
 	Ctxt    context.Context `json:"-"`
 	Members []*StorageNode  `json:"members,omitempty" mapstructure:"members"`
@@ -116,6 +131,11 @@ func (e *StoragePool) Set(ro *StoragePoolSetRequest) (*StoragePool, *ApiErrorRes
 
 }
 
+func (e *StoragePool) SetE(ro *StoragePoolSetRequest) (*StoragePool, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StoragePoolDeleteRequest struct {
 	Ctxt context.Context `json:"-"`
 }
@@ -134,3 +154,8 @@ func (e *StoragePool) Delete(ro *StoragePoolDeleteRequest) (*StoragePool, *ApiEr
 	}
 	return resp, nil, nil
 }
+
+func (e *StoragePool) DeleteE(ro *StoragePoolDeleteRequest) (*StoragePool, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
@@ -45,6 +45,11 @@ func newStorageTemplates(path string) *StorageTemplates {
 }
 
 func (e *StorageTemplates) Create(ro *StorageTemplatesCreateRequest) (*StorageTemplate, *ApiErrorResponse, error) {
+	if ro.PlacementPolicy != nil {
+		if err := requireApiVersion(ro.Ctxt, placementPolicyApiVersion, "storage_templates.placement_policy"); err != nil {
+			return nil, nil, err
+		}
+	}
 	gro := &greq.RequestOptions{JSON: ro}
 	rs, apierr, err := GetConn(ro.Ctxt).Post(ro.Ctxt, e.Path, gro)
 	if apierr != nil {
@@ -61,6 +66,11 @@ func (e *StorageTemplates) Create(ro *StorageTemplatesCreateRequest) (*StorageTe
 	return resp, nil, nil
 }
 
+func (e *StorageTemplates) CreateE(ro *StorageTemplatesCreateRequest) (*StorageTemplate, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StorageTemplatesListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -90,6 +100,11 @@ func (e *StorageTemplates) List(ro *StorageTemplatesListRequest) ([]*StorageTemp
 	return resp, nil, nil
 }
 
+func (e *StorageTemplates) ListE(ro *StorageTemplatesListRequest) ([]*StorageTemplate, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StorageTemplatesGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Name string          `json:"-"`
@@ -112,6 +127,11 @@ func (e *StorageTemplates) Get(ro *StorageTemplatesGetRequest) (*StorageTemplate
 	return resp, nil, nil
 }
 
+func (e *StorageTemplates) GetE(ro *StorageTemplatesGetRequest) (*StorageTemplate, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StorageTemplateSetRequest struct {
 	Ctxt            context.Context     `json:"-"`
 	Auth            Auth                `json:"auth,omitempty" mapstructure:"auth"`
@@ -137,6 +157,11 @@ func (e *StorageTemplate) Set(ro *StorageTemplateSetRequest) (*StorageTemplate,
 
 }
 
+func (e *StorageTemplate) SetE(ro *StorageTemplateSetRequest) (*StorageTemplate, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type StorageTemplateDeleteRequest struct {
 	Ctxt  context.Context `json:"-"`
 	Force bool            `json:"force,omitempty" mapstructure:"force"`
@@ -157,3 +182,8 @@ func (e *StorageTemplate) Delete(ro *StorageTemplateDeleteRequest) (*StorageTemp
 	RegisterStorageTemplateEndpoints(resp)
 	return resp, nil, nil
 }
+
+func (e *StorageTemplate) DeleteE(ro *StorageTemplateDeleteRequest) (*StorageTemplate, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
@@ -56,6 +56,11 @@ func (e *Subsystems) List(ro *SubsystemsListRequest) ([]*Subsystem, *ApiErrorRes
 	return resp, nil, nil
 }
 
+func (e *Subsystems) ListE(ro *SubsystemsListRequest) ([]*Subsystem, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type SubsystemsGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Id   string          `json:"-"`
@@ -76,3 +81,8 @@ func (e *Subsystems) Get(ro *SubsystemsGetRequest) (*Subsystem, *ApiErrorRespons
 	}
 	return resp, nil, nil
 }
+
+func (e *Subsystems) GetE(ro *SubsystemsGetRequest) (*Subsystem, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
@@ -74,6 +74,11 @@ func (e *System) Get(ro *SystemGetRequest) (*System, *ApiErrorResponse, error) {
 	return resp, nil, nil
 }
 
+func (e *System) GetE(ro *SystemGetRequest) (*System, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type SystemSetRequest struct {
 	Ctxt                             context.Context  `json:"-"`
 	AccessInterfaceAggrType          string           `json:"access_interface_aggr_type,omitempty" mapstructure:"access_interface_aggr_type"`
@@ -102,6 +107,11 @@ func (e *System) Set(ro *SystemSetRequest) (*System, *ApiErrorResponse, error) {
 
 }
 
+func (e *System) SetE(ro *SystemSetRequest) (*System, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type SystemReloadRequest struct {
 	Ctxt context.Context `json:"-"`
 }
@@ -122,3 +132,8 @@ func (e *System) Reload(ro *SystemReloadRequest) (*System, *ApiErrorResponse, er
 	RegisterSystemEndpoints(resp)
 	return resp, nil, nil
 }
+
+func (e *System) ReloadE(ro *SystemReloadRequest) (*System, error) {
+	resp, apierr, err := e.Reload(ro)
+	return resp, AsError(apierr, err)
+}
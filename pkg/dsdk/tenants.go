@@ -52,6 +52,11 @@ func (e *Tenants) Create(ro *TenantsCreateRequest) (*Tenant, *ApiErrorResponse,
 	return resp, nil, nil
 }
 
+func (e *Tenants) CreateE(ro *TenantsCreateRequest) (*Tenant, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type TenantsListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -80,6 +85,11 @@ func (e *Tenants) List(ro *TenantsListRequest) ([]*Tenant, *ApiErrorResponse, er
 	return resp, nil, nil
 }
 
+func (e *Tenants) ListE(ro *TenantsListRequest) ([]*Tenant, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type TenantsGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Path string          `json:"-"`
@@ -101,6 +111,11 @@ func (e *Tenants) Get(ro *TenantsGetRequest) (*Tenant, *ApiErrorResponse, error)
 	return resp, nil, nil
 }
 
+func (e *Tenants) GetE(ro *TenantsGetRequest) (*Tenant, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type TenantSetRequest struct {
 	Ctxt             context.Context `json:"-"`
 	Path             string          `json:"path,omitempty" mapstructure:"path"`
@@ -131,6 +146,11 @@ func (e *Tenant) Set(ro *TenantSetRequest) (*Tenant, *ApiErrorResponse, error) {
 
 }
 
+func (e *Tenant) SetE(ro *TenantSetRequest) (*Tenant, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type TenantDeleteRequest struct {
 	Ctxt context.Context `json:"-"`
 }
@@ -149,3 +169,8 @@ func (e *Tenant) Delete(ro *TenantDeleteRequest) (*Tenant, *ApiErrorResponse, er
 	}
 	return resp, nil, nil
 }
+
+func (e *Tenant) DeleteE(ro *TenantDeleteRequest) (*Tenant, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
@@ -0,0 +1,132 @@
+package dsdk
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+var ErrCertificatePinMismatch = errors.New("no certificate verified for the server matched a pinned public key")
+
+// TLSConfig controls how the certificate presented by the Datera cluster is
+// verified.  The zero value verifies it against the system's root CAs.
+type TLSConfig struct {
+	// CAFile is the path to a PEM encoded bundle of CAs trusted to sign the
+	// cluster certificate.  It is used instead of the system roots
+	CAFile string
+	// CAPEM is a PEM encoded bundle of CAs, used in addition to CAFile
+	CAPEM []byte
+	// CertFile and KeyFile are the PEM encoded client certificate and key
+	// presented to the cluster, if any
+	CertFile string
+	KeyFile  string
+	// PinnedSPKI are base64 encoded SHA-256 hashes of the SubjectPublicKeyInfo
+	// of certificates in the cluster's chain.  When set, the connection is only
+	// accepted if at least one certificate in the verified chain matches a
+	// pin.  With InsecureSkipVerify the chain isn't verified, so only the leaf
+	// certificate is checked
+	PinnedSPKI []string
+	// ServerName overrides the host name used to verify the certificate
+	ServerName string
+	// InsecureSkipVerify disables certificate chain and host name verification.
+	// Pins are still checked if provided
+	InsecureSkipVerify bool
+}
+
+// Build returns the *tls.Config described by t.  A nil t verifies the
+// certificate against the system's root CAs, like the zero value
+func (t *TLSConfig) Build() (*tls.Config, error) {
+	if t == nil {
+		t = &TLSConfig{}
+	}
+	conf := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+	if t.CAFile != "" || len(t.CAPEM) > 0 {
+		pool := x509.NewCertPool()
+		if t.CAFile != "" {
+			pem, err := ioutil.ReadFile(t.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("reading CA bundle: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in CA bundle %s", t.CAFile)
+			}
+		}
+		if len(t.CAPEM) > 0 && !pool.AppendCertsFromPEM(t.CAPEM) {
+			return nil, fmt.Errorf("no certificates found in CAPEM")
+		}
+		conf.RootCAs = pool
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	if len(t.PinnedSPKI) > 0 {
+		pins := NewStringSet(len(t.PinnedSPKI), t.PinnedSPKI...)
+		insecure := t.InsecureSkipVerify
+		conf.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			return verifyPins(pins, rawCerts, verifiedChains, insecure)
+		}
+	}
+	return conf, nil
+}
+
+// SPKIHash returns the base64 encoded SHA-256 hash of the certificate's
+// SubjectPublicKeyInfo in the format expected by TLSConfig.PinnedSPKI
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// verifyPins checks the pins against certificates the server can't choose
+// freely.  The server appending the cluster's public certificate to its own
+// chain mustn't be enough, so only the verified chains are checked, or the
+// leaf certificate when the chain isn't verified
+func verifyPins(pins *StringSet, rawCerts [][]byte, verifiedChains [][]*x509.Certificate, insecure bool) error {
+	if insecure {
+		if len(rawCerts) == 0 {
+			return ErrCertificatePinMismatch
+		}
+		leaf, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+		if pins.Contains(SPKIHash(leaf)) {
+			return nil
+		}
+		return ErrCertificatePinMismatch
+	}
+	for _, chain := range verifiedChains {
+		for _, cert := range chain {
+			if pins.Contains(SPKIHash(cert)) {
+				return nil
+			}
+		}
+	}
+	return ErrCertificatePinMismatch
+}
+
+// newTLSClient returns an *http.Client using its own transport configured with
+// t, leaving http.DefaultTransport untouched
+func newTLSClient(t *TLSConfig) (*http.Client, error) {
+	conf, err := t.Build()
+	if err != nil {
+		return nil, err
+	}
+	tr := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if dt, ok := http.DefaultTransport.(*http.Transport); ok {
+		tr = dt.Clone()
+	}
+	tr.TLSClientConfig = conf
+	return &http.Client{Transport: tr}, nil
+}
@@ -0,0 +1,129 @@
+package dsdk
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	stdlog "log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	udc "github.com/Datera/go-udc/pkg/udc"
+)
+
+func TestTLSConfig(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ErrorLog = stdlog.New(ioutil.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+	cert := srv.Certificate()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+
+	tests := []struct {
+		name    string
+		conf    *TLSConfig
+		wantErr bool
+	}{
+		{name: "system roots reject self signed", conf: &TLSConfig{}, wantErr: true},
+		{name: "custom CA bundle", conf: &TLSConfig{CAPEM: caPEM}},
+		{name: "insecure", conf: &TLSConfig{InsecureSkipVerify: true}},
+		{name: "matching pin", conf: &TLSConfig{CAPEM: caPEM, PinnedSPKI: []string{SPKIHash(cert)}}},
+		{name: "mismatched pin", conf: &TLSConfig{InsecureSkipVerify: true, PinnedSPKI: []string{"bm9wZQ=="}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newTLSClient(tt.conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Get(srv.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got err %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if tc := http.DefaultTransport.(*http.Transport).TLSClientConfig; tc != nil && tc.InsecureSkipVerify {
+		t.Errorf("http.DefaultTransport should not be modified")
+	}
+}
+
+func TestTLSConfigNil(t *testing.T) {
+	conf, err := (*TLSConfig)(nil).Build()
+	if err != nil || conf.InsecureSkipVerify {
+		t.Errorf("expected a nil TLSConfig to verify certificates, got %#v, %v", conf, err)
+	}
+	if _, err = NewApiConnectionWithTLS(&udc.UDC{MgmtIp: "127.0.0.1", ApiVersion: "2.2"}, true, nil); err != nil {
+		t.Errorf("expected a connection with the default TLS config, got %v", err)
+	}
+}
+
+// TestTLSPinAppendedCertificate checks a server can't pass the pin check by
+// presenting its own certificate followed by the cluster's public one
+func TestTLSPinAppendedCertificate(t *testing.T) {
+	cluster := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	cluster.StartTLS()
+	defer cluster.Close()
+	pinned := cluster.Certificate()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "attacker"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mitm := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	mitm.Config.ErrorLog = stdlog.New(ioutil.Discard, "", 0)
+	mitm.TLS = &tls.Config{Certificates: []tls.Certificate{{
+		Certificate: [][]byte{der, pinned.Raw},
+		PrivateKey:  key,
+	}}}
+	mitm.StartTLS()
+	defer mitm.Close()
+	attackerPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	for name, conf := range map[string]*TLSConfig{
+		"insecure":       {InsecureSkipVerify: true, PinnedSPKI: []string{SPKIHash(pinned)}},
+		"verified chain": {CAPEM: attackerPEM, PinnedSPKI: []string{SPKIHash(pinned)}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, err := newTLSClient(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Get(mitm.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if !errors.Is(err, ErrCertificatePinMismatch) {
+				t.Errorf("expected ErrCertificatePinMismatch, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package dsdk
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracer creates a span for every request the SDK sends to the Datera API and
+// propagates the trace context to it.  It is small enough to be backed by an
+// OpenTelemetry trace.Tracer and TextMapPropagator without the SDK depending
+// on OpenTelemetry, see the README for an adapter.
+type Tracer interface {
+	// Start begins a span that is a child of any span found in ctxt
+	Start(ctxt context.Context, name string) (context.Context, Span)
+	// Inject writes the trace context of ctxt, eg. the W3C traceparent and
+	// tracestate headers, into header
+	Inject(ctxt context.Context, header http.Header)
+}
+
+// Span is a single traced request
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// Attributes set on every span
+const (
+	SpanAttrMethod       = "http.method"
+	SpanAttrURL          = "http.url"
+	SpanAttrStatusCode   = "http.status_code"
+	SpanAttrRoute        = "datera.route"
+	SpanAttrRequestId    = "datera.request_id"
+	SpanAttrTraceId      = "datera.trace_id"
+	SpanAttrRetryAttempt = "datera.retry_attempt"
+	SpanAttrEndpoint     = "datera.endpoint"
+
+	// SpanAttrApiReqId is only set on requests that failed with an API error,
+	// the cluster doesn't return an api_req_id with successful responses
+	SpanAttrApiReqId = "datera.api_req_id"
+)
+
+// SetTracer enables tracing of every request made with this connection.  It
+// should be called before the connection is shared between goroutines
+func (c *ApiConnection) SetTracer(t Tracer) {
+	c.tracer = t
+}
+
+func withAttempt(ctxt context.Context, attempt int) context.Context {
+	return context.WithValue(ctxt, attemptCtxKey, attempt)
+}
+
+// attemptFrom returns which attempt at a request is being made, starting at 1
+func attemptFrom(ctxt context.Context) int {
+	if a, ok := ctxt.Value(attemptCtxKey).(int); ok {
+		return a
+	}
+	return 1
+}
+
+// startSpan starts the span for a single request and injects its trace context
+// into the request headers.  It returns nil when tracing is disabled
+func (c *ApiConnection) startSpan(ctxt context.Context, route string, headers map[string]string) Span {
+	if c.tracer == nil {
+		return nil
+	}
+	sctxt, span := c.tracer.Start(ctxt, route)
+	h := http.Header{}
+	c.tracer.Inject(sctxt, h)
+	for k := range h {
+		headers[k] = h.Get(k)
+	}
+	return span
+}
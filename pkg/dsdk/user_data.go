@@ -46,6 +46,11 @@ func (e *UserDatas) Set(ud *UserDataSetRequest) (*UserData, *ApiErrorResponse, e
 	return resp, nil, nil
 }
 
+func (e *UserDatas) SetE(ud *UserDataSetRequest) (*UserData, error) {
+	resp, apierr, err := e.Set(ud)
+	return resp, AsError(apierr, err)
+}
+
 // UserDatasListRequest lists all custom user data on all apps within a tenant
 // Params is the normal ListParams, but Sort isn't used/supported.
 type UserDatasListRequest struct {
@@ -78,6 +83,11 @@ func (e *UserDatas) List(udlr *UserDatasListRequest) ([]*UserData, *ApiErrorResp
 	return resp, nil, nil
 }
 
+func (e *UserDatas) ListE(udlr *UserDatasListRequest) ([]*UserData, error) {
+	resp, apierr, err := e.List(udlr)
+	return resp, AsError(apierr, err)
+}
+
 // UserDataGetRequest gets one AppInstance's uploaded user data
 type UserDataGetRequest struct {
 	Ctxt context.Context     `json:"-"`
@@ -98,3 +108,8 @@ func (e *UserDatas) Get(ud *UserDataGetRequest) (*UserData, *ApiErrorResponse, e
 	}
 	return resp, nil, nil
 }
+
+func (e *UserDatas) GetE(ud *UserDataGetRequest) (*UserData, error) {
+	resp, apierr, err := e.Get(ud)
+	return resp, AsError(apierr, err)
+}
@@ -37,7 +37,7 @@ const (
 var (
 	src                = rand.NewSource(time.Now().UnixNano())
 	execCommand        = exec.Command
-	resourceNamesRegex = regexp.MustCompile(`^(storage_nodes|nics|hdds|boot_drives|subsystem_states|flash_devices|remote_providers|operations|media_policies|failure_domains|initiators|initiator_groups|members|acl_policy|storage_instances|volumes|performance_policy|app_instances|snapshot_policies|refresh|snapshots|app_instance_user_data|user_data|app_instance_ecosystem_data|ecosystem_data|template_override|system|http_proxy|ntp_servers|dns|servers|search_domains|network|mapping|access_vip|network_paths|mgmt_vip|internal_network|ldap_servers|test_bind|list_users|list_groups|resolve_user|user_scan|groups|ous|witness_policy|smtp_configs|init|config|upgrade|available|access_network_ip_pools|users|roles|app_templates|storage_templates|volume_templates|auth|placement_policies|tenants|root|snmp_policy|events|alerts|system|monitoring|policies|default|send_test_event|metrics|hw|io|latest|time|api|network_diagnostics|run|status|search|login|logout|userinfo)$`)
+	resourceNamesRegex = regexp.MustCompile(`^(storage_nodes|nics|hdds|boot_drives|subsystem_states|flash_devices|remote_providers|operations|media_policies|failure_domains|initiators|initiator_groups|members|acl_policy|storage_instances|volumes|performance_policy|app_instances|snapshot_policies|refresh|snapshots|app_instance_user_data|user_data|app_instance_ecosystem_data|ecosystem_data|template_override|system|http_proxy|ntp_servers|dns|servers|search_domains|network|mapping|access_vip|network_paths|mgmt_vip|internal_network|ldap_servers|test_bind|list_users|list_groups|resolve_user|user_scan|groups|ous|witness_policy|smtp_configs|init|config|upgrade|available|access_network_ip_pools|users|roles|app_templates|storage_templates|volume_templates|auth|placement_policies|tenants|root|snmp_policy|events|alerts|system|monitoring|policies|default|send_test_event|metrics|hw|io|latest|time|api|network_diagnostics|run|status|search|login|logout|userinfo|logs_upload|api_versions)$`)
 )
 
 func canonicalizeRoute(route, apiVersion string) string {
@@ -50,6 +50,8 @@ func canonicalizeRoute(route, apiVersion string) string {
 	return strings.Join(parts, "/")
 }
 
+// Log returns an entry of the global logrus logger with where it was called
+// from.  The SDK itself logs through the Logger of each connection
 func Log() *log.Entry {
 	return DecorateRuntimeContext(log.WithFields(log.Fields{}))
 }
@@ -328,17 +330,11 @@ func FillStruct(m map[string]interface{}, s interface{}) error {
 	return mapstructure.Decode(m, s)
 }
 
+// GetConn returns the connection of ctxt, or nil if there is none.  Requests
+// made with a nil *ApiConnection fail with ErrNoConnection, see ConnectionFrom
 func GetConn(ctxt context.Context) *ApiConnection {
-	defer recoverConn()
-	conn := ctxt.Value("conn")
-	return conn.(*ApiConnection)
-}
-
-func recoverConn() {
-	if r := recover(); r != nil {
-		panic("You MUST provide a context object containing a *ApiConnection for requests." +
-			"Use sdk.Context() to obtain the context object")
-	}
+	conn, _ := ConnectionFrom(ctxt)
+	return conn
 }
 
 func Pretty(i interface{}) string {
@@ -346,6 +342,9 @@ func Pretty(i interface{}) string {
 	return string(s)
 }
 
+// LogFormatter formats logrus entries the way the SDK used to configure the
+// global logger, applications that want it can use
+// log.SetFormatter(&dsdk.LogFormatter{})
 type LogFormatter struct {
 }
 
@@ -382,21 +381,16 @@ func RunCmd(cmd ...string) (string, error) {
 			ncmd = append(ncmd, c)
 		}
 	}
-	Log().Debugf("Running command: [%s]", strings.Join(ncmd, " "))
+	defaultLog().Debugf("Running command: [%s]", strings.Join(ncmd, " "))
 	prefix := ncmd[0]
 	ncmd = ncmd[1:]
 	c := execCommand(prefix, ncmd...)
 	out, err := c.CombinedOutput()
 	sout := string(out)
-	Log().Debug(sout)
+	defaultLog().Debugf("%s", sout)
 	return sout, err
 }
 
-func init() {
-	log.SetFormatter(&LogFormatter{})
-	log.SetLevel(log.DebugLevel)
-}
-
 func formatQueryParams(gro *greq.RequestOptions, v reflect.Value, t reflect.Type) {
 	// Formats the Query Params of the Request Option to include
 	// all the fields (name - value) as query params in the URL
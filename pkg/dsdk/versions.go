@@ -0,0 +1,172 @@
+package dsdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	greq "github.com/levigross/grequests"
+)
+
+// ApiVersionAuto in udc.UDC.ApiVersion makes the SDK negotiate the API version
+// with the cluster when it is created, an empty ApiVersion does the same
+const ApiVersionAuto = "auto"
+
+// KnownApiVersions are the API versions this SDK can talk, oldest first
+var KnownApiVersions = []string{"2", "2.1", "2.2"}
+
+// placementPolicyApiVersion is the API version placement policies, and the
+// placement_policy field of volumes and templates, were added in
+const placementPolicyApiVersion = "2.2"
+
+// ErrUnsupportedApiVersion is matched by every UnsupportedApiVersionError
+var ErrUnsupportedApiVersion = errors.New("unsupported API version")
+
+// UnsupportedApiVersionError is returned when a call or field needs a newer
+// API version than the one the connection uses
+type UnsupportedApiVersionError struct {
+	Feature  string
+	Required string
+	Current  string
+}
+
+func (e *UnsupportedApiVersionError) Error() string {
+	return fmt.Sprintf("%s requires API version %s or newer, connected with %s", e.Feature, e.Required, e.Current)
+}
+
+func (e *UnsupportedApiVersionError) Is(target error) bool {
+	return target == ErrUnsupportedApiVersion
+}
+
+// compareApiVersions returns -1, 0 or 1 if a is older, the same or newer than b.
+// Versions may have a leading "v", missing components count as 0 so "2" and
+// "2.0" are the same version
+func compareApiVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var na, nb int
+		if i < len(pa) {
+			na, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			nb, _ = strconv.Atoi(pb[i])
+		}
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// ApiVersion returns the API version the connection uses
+func (c *ApiConnection) ApiVersion() string {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	return c.apiVersion
+}
+
+// SetApiVersion changes the API version used by the connection.  Requests
+// already in flight finish with the previous version, the endpoints keep
+// their health and the ones found by discovery
+func (c *ApiConnection) SetApiVersion(v string) error {
+	v = strings.TrimPrefix(v, "v")
+	if _, err := url.Parse("/v" + v); err != nil {
+		return err
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.apiVersion = v
+	c.endpoints.setApiVersion(v)
+	return nil
+}
+
+// ApiVersionAtLeast reports whether the connection uses API version v or newer
+func (c *ApiConnection) ApiVersionAtLeast(v string) bool {
+	return compareApiVersions(c.ApiVersion(), v) >= 0
+}
+
+// RequireApiVersion returns an UnsupportedApiVersionError naming feature when
+// the connection uses an API version older than v
+func (c *ApiConnection) RequireApiVersion(v, feature string) error {
+	if c.ApiVersionAtLeast(v) {
+		return nil
+	}
+	return &UnsupportedApiVersionError{Feature: feature, Required: v, Current: c.ApiVersion()}
+}
+
+// requireApiVersion is RequireApiVersion for endpoint calls.  Calls without a
+// connection are let through for the request to fail with ErrNoConnection
+func requireApiVersion(ctxt context.Context, v, feature string) error {
+	c := GetConn(ctxt)
+	if c == nil {
+		return nil
+	}
+	return c.RequireApiVersion(v, feature)
+}
+
+func (c *ApiConnection) apiVersions(ctxt context.Context) (*ApiVersions, error) {
+	gurl := *c.endpoints.pick()
+	gurl.Path = "/api_versions"
+	ro := &greq.RequestOptions{
+		HTTPClient: c.httpClient,
+		Context:    ctxt,
+		Headers:    map[string]string{"Datera-Driver": DateraDriver},
+	}
+	resp, err := greq.DoRegularRequest("GET", gurl.String(), ro)
+	apierr, err := c.translateErrors(ctxt, resp, err)
+	if err = AsError(apierr, err); err != nil {
+		return nil, err
+	}
+	apiv := &ApiVersions{}
+	if err = resp.JSON(apiv); err != nil {
+		return nil, err
+	}
+	return apiv, nil
+}
+
+// FetchApiVersions returns the API versions supported by the cluster without
+// their "v" prefix, eg. ["2", "2.1", "2.2"]
+func (c *ApiConnection) FetchApiVersions(ctxt context.Context) ([]string, error) {
+	apiv, err := c.apiVersions(ctxt)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, len(apiv.ApiVersions))
+	for i, v := range apiv.ApiVersions {
+		versions[i] = strings.TrimPrefix(v, "v")
+	}
+	return versions, nil
+}
+
+// NegotiateApiVersion switches the connection to the newest API version
+// supported by both the cluster and the SDK and returns it.  It should be
+// called before the connection is shared between goroutines
+func (c *ApiConnection) NegotiateApiVersion(ctxt context.Context) (string, error) {
+	versions, err := c.FetchApiVersions(ctxt)
+	if err != nil {
+		return "", err
+	}
+	best := ""
+	for _, v := range versions {
+		for _, k := range KnownApiVersions {
+			if compareApiVersions(v, k) == 0 && (best == "" || compareApiVersions(k, best) > 0) {
+				best = k
+			}
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("%w: cluster supports %v, SDK supports %v", ErrUnsupportedApiVersion, versions, KnownApiVersions)
+	}
+	if err = c.SetApiVersion(best); err != nil {
+		return "", err
+	}
+	c.log(ctxt).Debugf("Negotiated API version %s, cluster supports %v", best, versions)
+	return best, nil
+}
@@ -0,0 +1,122 @@
+package dsdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	udc "github.com/Datera/go-udc/pkg/udc"
+	greq "github.com/levigross/grequests"
+)
+
+func TestCompareApiVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"2.2", "2.1", 1},
+		{"2.1", "2.2", -1},
+		{"v2.2", "2.2", 0},
+		{"2", "2.0", 0},
+		{"2.10", "2.2", 1},
+		{"2", "2.1", -1},
+	} {
+		if got := compareApiVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareApiVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestRequireApiVersion(t *testing.T) {
+	c := &ApiConnection{apiVersion: "2.1", m: &sync.RWMutex{}}
+	if err := c.RequireApiVersion("2.1", "feature"); err != nil {
+		t.Errorf("expected 2.1 to be supported, got %s", err)
+	}
+	err := c.RequireApiVersion("2.2", "app_instances.foo")
+	if !errors.Is(err, ErrUnsupportedApiVersion) {
+		t.Errorf("expected ErrUnsupportedApiVersion, got %v", err)
+	}
+	if err.Error() != "app_instances.foo requires API version 2.2 or newer, connected with 2.1" {
+		t.Errorf("unexpected message %q", err)
+	}
+}
+
+func TestSetApiVersionConcurrent(t *testing.T) {
+	paths := make(chan string, 200)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+		fmt.Fprint(w, `{"data": {}}`)
+	}))
+	defer ts.Close()
+	host := strings.TrimPrefix(ts.URL, "http://")
+	c, err := NewApiConnectionWithHTTPClientE(&udc.UDC{MgmtIp: host, ApiVersion: "2.1"}, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.endpoints.add(&url.URL{Scheme: "http", Host: "127.0.0.2:7717", Path: "/v2.1"})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			c.canonicalRoute("app_instances/my-ai")
+			c.endpoints.pick()
+			if _, err := c.do(context.Background(), "GET", "system", &greq.RequestOptions{}, &ApiOuter{}, !canRetry, !isSensitive, !allowLogin); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if err := c.SetApiVersion(KnownApiVersions[i%len(KnownApiVersions)]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+
+	// the version is changed in place, keeping discovered endpoints and
+	// their health
+	c.endpoints.markDown(c.endpoints.pick())
+	if err := c.SetApiVersion("v2.2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.endpoints.hosts(); len(got) != 2 || got[1] != "127.0.0.2:7717" {
+		t.Errorf("expected the discovered endpoint to be kept, got %v", got)
+	}
+	if u := c.endpoints.pick(); u.Host != "127.0.0.2:7717" || u.Path != "/v2.2" {
+		t.Errorf("expected the endpoint that is down to stay down, got %s", u)
+	}
+	close(paths)
+	for p := range paths {
+		if !strings.HasPrefix(p, "/v2") {
+			t.Errorf("unexpected request to %s", p)
+		}
+	}
+}
+
+func TestPlacementPolicyApiVersion(t *testing.T) {
+	// nothing listens on the endpoint, a request that was sent would fail
+	// with a connection error
+	c, err := NewApiConnectionWithHTTPClientE(&udc.UDC{MgmtIp: "127.0.0.1:1", ApiVersion: "2.1"}, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctxt := WithConnection(context.Background(), c)
+	if _, _, err := newPlacementPolicies("/").List(&PlacementPoliciesListRequest{Ctxt: ctxt}); !errors.Is(err, ErrUnsupportedApiVersion) {
+		t.Errorf("expected ErrUnsupportedApiVersion, got %v", err)
+	}
+	vols := newVolumes("/app_instances/my-ai/storage_instances/si-1")
+	_, err = vols.CreateE(&VolumesCreateRequest{Ctxt: ctxt, Name: "vol-1", PlacementPolicy: &PlacementPolicy{ResolvedPath: "/placement_policies/hybrid"}})
+	if !errors.Is(err, ErrUnsupportedApiVersion) {
+		t.Errorf("expected ErrUnsupportedApiVersion, got %v", err)
+	}
+	if err.Error() != "volumes.placement_policy requires API version 2.2 or newer, connected with 2.1" {
+		t.Errorf("unexpected message %q", err)
+	}
+}
@@ -42,6 +42,11 @@ func newVolumeTemplates(path string) *VolumeTemplates {
 }
 
 func (e *VolumeTemplates) Create(ro *VolumeTemplatesCreateRequest) (*VolumeTemplate, *ApiErrorResponse, error) {
+	if ro.PlacementPolicy != nil {
+		if err := requireApiVersion(ro.Ctxt, placementPolicyApiVersion, "volume_templates.placement_policy"); err != nil {
+			return nil, nil, err
+		}
+	}
 	gro := &greq.RequestOptions{JSON: ro}
 	rs, apierr, err := GetConn(ro.Ctxt).Post(ro.Ctxt, e.Path, gro)
 	if apierr != nil {
@@ -58,6 +63,11 @@ func (e *VolumeTemplates) Create(ro *VolumeTemplatesCreateRequest) (*VolumeTempl
 	return resp, nil, nil
 }
 
+func (e *VolumeTemplates) CreateE(ro *VolumeTemplatesCreateRequest) (*VolumeTemplate, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type VolumeTemplatesListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -87,6 +97,11 @@ func (e *VolumeTemplates) List(ro *VolumeTemplatesListRequest) ([]*VolumeTemplat
 	return resp, nil, nil
 }
 
+func (e *VolumeTemplates) ListE(ro *VolumeTemplatesListRequest) ([]*VolumeTemplate, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type VolumeTemplatesGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Name string          `json:"-"`
@@ -109,6 +124,11 @@ func (e *VolumeTemplates) Get(ro *VolumeTemplatesGetRequest) (*VolumeTemplate, *
 	return resp, nil, nil
 }
 
+func (e *VolumeTemplates) GetE(ro *VolumeTemplatesGetRequest) (*VolumeTemplate, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type VolumeTemplateSetRequest struct {
 	Ctxt            context.Context  `json:"-"`
 	PlacementMode   string           `json:"placement_mode,omitempty" mapstructure:"placement_mode"`
@@ -119,6 +139,11 @@ type VolumeTemplateSetRequest struct {
 }
 
 func (e *VolumeTemplate) Set(ro *VolumeTemplateSetRequest) (*VolumeTemplate, *ApiErrorResponse, error) {
+	if ro.PlacementPolicy != nil {
+		if err := requireApiVersion(ro.Ctxt, placementPolicyApiVersion, "volume_templates.placement_policy"); err != nil {
+			return nil, nil, err
+		}
+	}
 	gro := &greq.RequestOptions{JSON: ro}
 	rs, apierr, err := GetConn(ro.Ctxt).Put(ro.Ctxt, e.Path, gro)
 	if apierr != nil {
@@ -136,6 +161,11 @@ func (e *VolumeTemplate) Set(ro *VolumeTemplateSetRequest) (*VolumeTemplate, *Ap
 
 }
 
+func (e *VolumeTemplate) SetE(ro *VolumeTemplateSetRequest) (*VolumeTemplate, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type VolumeTemplateDeleteRequest struct {
 	Ctxt context.Context `json:"-"`
 }
@@ -155,3 +185,8 @@ func (e *VolumeTemplate) Delete(ro *VolumeTemplateDeleteRequest) (*VolumeTemplat
 	RegisterVolumeTemplateEndpoints(resp)
 	return resp, nil, nil
 }
+
+func (e *VolumeTemplate) DeleteE(ro *VolumeTemplateDeleteRequest) (*VolumeTemplate, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
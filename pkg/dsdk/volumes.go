@@ -66,6 +66,11 @@ func newVolumes(path string) *Volumes {
 }
 
 func (e *Volumes) Create(ro *VolumesCreateRequest) (*Volume, *ApiErrorResponse, error) {
+	if ro.PlacementPolicy != nil {
+		if err := requireApiVersion(ro.Ctxt, placementPolicyApiVersion, "volumes.placement_policy"); err != nil {
+			return nil, nil, err
+		}
+	}
 	gro := &greq.RequestOptions{JSON: ro}
 	rs, apierr, err := GetConn(ro.Ctxt).Post(ro.Ctxt, e.Path, gro)
 	if apierr != nil {
@@ -82,6 +87,11 @@ func (e *Volumes) Create(ro *VolumesCreateRequest) (*Volume, *ApiErrorResponse,
 	return resp, nil, nil
 }
 
+func (e *Volumes) CreateE(ro *VolumesCreateRequest) (*Volume, error) {
+	resp, apierr, err := e.Create(ro)
+	return resp, AsError(apierr, err)
+}
+
 type VolumesListRequest struct {
 	Ctxt   context.Context `json:"-"`
 	Params ListParams      `json:"params,omitempty"`
@@ -111,6 +121,11 @@ func (e *Volumes) List(ro *VolumesListRequest) ([]*Volume, *ApiErrorResponse, er
 	return resp, nil, nil
 }
 
+func (e *Volumes) ListE(ro *VolumesListRequest) ([]*Volume, error) {
+	resp, apierr, err := e.List(ro)
+	return resp, AsError(apierr, err)
+}
+
 type VolumesGetRequest struct {
 	Ctxt context.Context `json:"-"`
 	Name string          `json:"-"`
@@ -133,6 +148,11 @@ func (e *Volumes) Get(ro *VolumesGetRequest) (*Volume, *ApiErrorResponse, error)
 	return resp, nil, nil
 }
 
+func (e *Volumes) GetE(ro *VolumesGetRequest) (*Volume, error) {
+	resp, apierr, err := e.Get(ro)
+	return resp, AsError(apierr, err)
+}
+
 type VolumeSetRequest struct {
 	Ctxt            context.Context  `json:"-"`
 	ReplicaCount    int              `json:"replica_count,omitempty" mapstructure:"replica_count"`
@@ -144,6 +164,11 @@ type VolumeSetRequest struct {
 }
 
 func (e *Volume) Set(ro *VolumeSetRequest) (*Volume, *ApiErrorResponse, error) {
+	if ro.PlacementPolicy != nil {
+		if err := requireApiVersion(ro.Ctxt, placementPolicyApiVersion, "volumes.placement_policy"); err != nil {
+			return nil, nil, err
+		}
+	}
 	gro := &greq.RequestOptions{JSON: ro}
 	rs, apierr, err := GetConn(ro.Ctxt).Put(ro.Ctxt, e.Path, gro)
 	if apierr != nil {
@@ -160,6 +185,11 @@ func (e *Volume) Set(ro *VolumeSetRequest) (*Volume, *ApiErrorResponse, error) {
 	return resp, nil, nil
 }
 
+func (e *Volume) SetE(ro *VolumeSetRequest) (*Volume, error) {
+	resp, apierr, err := e.Set(ro)
+	return resp, AsError(apierr, err)
+}
+
 type VolumeDeleteRequest struct {
 	Ctxt context.Context `json:"-"`
 }
@@ -180,6 +210,11 @@ func (e *Volume) Delete(ro *VolumeDeleteRequest) (*Volume, *ApiErrorResponse, er
 	return resp, nil, nil
 }
 
+func (e *Volume) DeleteE(ro *VolumeDeleteRequest) (*Volume, error) {
+	resp, apierr, err := e.Delete(ro)
+	return resp, AsError(apierr, err)
+}
+
 type VolumeReloadRequest struct {
 	Ctxt context.Context `json:"-"`
 }
@@ -200,3 +235,8 @@ func (e *Volume) Reload(ro *VolumeReloadRequest) (*Volume, *ApiErrorResponse, er
 	RegisterVolumeEndpoints(resp)
 	return resp, nil, nil
 }
+
+func (e *Volume) ReloadE(ro *VolumeReloadRequest) (*Volume, error) {
+	resp, apierr, err := e.Reload(ro)
+	return resp, AsError(apierr, err)
+}
@@ -0,0 +1,257 @@
+// Package dsdktest helps testing code built on the dsdk package without a
+// Datera cluster
+package dsdktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	dsdk "github.com/tjcelaya/go-datera/pkg/dsdk"
+)
+
+// Mode selects whether a Recorder talks to a cluster or replays a cassette
+type Mode int
+
+const (
+	// ModeReplay answers requests from the cassette without any network
+	// access
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the cluster and writes the interactions to
+	// the cassette when the Recorder is stopped
+	ModeRecord
+)
+
+// EnvCassetteMode is the environment variable read by ModeFromEnv
+const EnvCassetteMode = "DSDK_CASSETTE_MODE"
+
+// ModeFromEnv returns ModeRecord when DSDK_CASSETTE_MODE is "record" and
+// ModeReplay otherwise, so test suites replay by default and can be pointed
+// at a live cluster to refresh their cassettes
+func ModeFromEnv() Mode {
+	if strings.EqualFold(os.Getenv(EnvCassetteMode), "record") {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// ErrNoInteraction is returned in ModeReplay for requests the cassette has no
+// unused interaction for
+var ErrNoInteraction = errors.New("no recorded interaction")
+
+// RedactedFields are masked in recorded payloads in addition to
+// dsdk.DefaultRedactedFields.  "key" is the apikey returned by a login
+var RedactedFields = []string{"key"}
+
+// Cassette is the set of interactions recorded for a test
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and the response it got
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+
+	used bool
+}
+
+// RecordedRequest is the part of a request that is recorded
+type RecordedRequest struct {
+	Method string `json:"method"`
+	// URL is the path and query of the request, the host is left out so
+	// cassettes replay against any management IP
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the part of a response that is recorded
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// Recorder is an http.RoundTripper recording interactions with a cluster to
+// a cassette file, or replaying them from it
+//
+//	rec, err := dsdktest.NewRecorder("testdata/list_ais.json", dsdktest.ModeFromEnv())
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer rec.Stop()
+//	sdk, err := dsdk.NewSDKWithHTTPClient(conf, false, rec.Client())
+type Recorder struct {
+	// Base sends the requests in ModeRecord, http.DefaultTransport when nil
+	Base http.RoundTripper
+
+	m        sync.Mutex
+	path     string
+	mode     Mode
+	cassette *Cassette
+}
+
+// NewRecorder returns a Recorder for the cassette at path.  In ModeReplay the
+// cassette must exist
+func NewRecorder(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode, cassette: &Cassette{}}
+	if mode == ModeRecord {
+		return r, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, r.cassette); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Mode returns whether the Recorder records or replays
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Client returns an http.Client sending its requests through the Recorder
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip records or replays req
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	rreq := RecordedRequest{
+		Method: req.Method,
+		URL:    requestURL(req.URL),
+		Body:   string(dsdk.RedactPayload(body, RedactedFields...)),
+	}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		rreq.Body = string(dsdk.RedactForm(body, RedactedFields...))
+	}
+	if r.mode == ModeReplay {
+		return r.replay(req, rreq)
+	}
+
+	rreq.Headers = dsdk.RedactHeaders(req.Header)
+	base := r.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		// transport errors aren't recorded, replaying them isn't deterministic
+		return nil, err
+	}
+	rbody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(rbody))
+
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+		Request: rreq,
+		Response: RecordedResponse{
+			Status:  resp.StatusCode,
+			Headers: dsdk.RedactHeaders(resp.Header),
+			Body:    string(dsdk.RedactPayload(rbody, RedactedFields...)),
+		},
+	})
+	return resp, nil
+}
+
+// replay answers req with the first unused interaction with the same method,
+// path and query
+func (r *Recorder) replay(req *http.Request, rreq RecordedRequest) (*http.Response, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	for _, in := range r.cassette.Interactions {
+		if in.used || in.Request.Method != rreq.Method || in.Request.URL != rreq.URL {
+			continue
+		}
+		in.used = true
+		header := http.Header{}
+		for k, v := range in.Response.Headers {
+			header[k] = append([]string(nil), v...)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+			StatusCode:    in.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(strings.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w for %s %s in %s", ErrNoInteraction, rreq.Method, rreq.URL, r.path)
+}
+
+// Unused returns the interactions that weren't replayed, which usually means
+// the code under test stopped making requests it made when recording
+func (r *Recorder) Unused() []*Interaction {
+	r.m.Lock()
+	defer r.m.Unlock()
+	unused := []*Interaction{}
+	for _, in := range r.cassette.Interactions {
+		if !in.used {
+			unused = append(unused, in)
+		}
+	}
+	return unused
+}
+
+// Stop writes the cassette in ModeRecord, it does nothing in ModeReplay
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.m.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.m.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, data, 0644)
+}
+
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// requestURL returns the path and the query of u with its params sorted
+func requestURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+	return u.Path + "?" + u.Query().Encode()
+}
@@ -0,0 +1,97 @@
+package dsdktest
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	udc "github.com/Datera/go-udc/pkg/udc"
+	dsdk "github.com/tjcelaya/go-datera/pkg/dsdk"
+)
+
+func listAppInstances(t *testing.T, rec *Recorder, mgmtIp string) []string {
+	sdk, err := dsdk.NewSDKWithHTTPClient(&udc.UDC{
+		MgmtIp:     mgmtIp,
+		Username:   "admin",
+		Password:   "hunter2",
+		ApiVersion: "2.2",
+	}, false, rec.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ais, err := sdk.AppInstances.ListE(&dsdk.AppInstancesListRequest{
+		Ctxt:   sdk.NewContext(),
+		Params: dsdk.ListParams{Filter: "match(name,my-.*)"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, ai := range ais {
+		names = append(names, ai.Name)
+	}
+	return names
+}
+
+func TestRecorder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v2.2/login":
+			json.NewEncoder(w).Encode(map[string]interface{}{"key": "the-secret-key"})
+		case "/v2.2/app_instances":
+			if r.Header.Get("Auth-Token") != "the-secret-key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": []interface{}{map[string]interface{}{"name": "my-ai", "access_key": "s3cr3t"}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	cassette := filepath.Join(t.TempDir(), "testdata", "list.json")
+
+	rec, err := NewRecorder(cassette, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := listAppInstances(t, rec, strings.TrimPrefix(ts.URL, "http://"))
+	if err = rec.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	ts.Close()
+
+	data, err := ioutil.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"hunter2", "the-secret-key", "s3cr3t"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("%s wasn't redacted from the cassette", secret)
+		}
+	}
+
+	// the server is gone and the cassette is replayed against another host
+	rec, err = NewRecorder(cassette, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := listAppInstances(t, rec, "192.0.2.1")
+	if len(recorded) != 1 || len(replayed) != 1 || recorded[0] != replayed[0] {
+		t.Errorf("expected %v, got %v", recorded, replayed)
+	}
+	if unused := rec.Unused(); len(unused) != 0 {
+		t.Errorf("expected every interaction to be replayed, %d weren't", len(unused))
+	}
+	req, _ := http.NewRequest("GET", "http://192.0.2.1/v2.2/system", nil)
+	if _, err = rec.RoundTrip(req); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("expected ErrNoInteraction, got %v", err)
+	}
+}
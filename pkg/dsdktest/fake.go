@@ -0,0 +1,717 @@
+package dsdktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	udc "github.com/Datera/go-udc/pkg/udc"
+	dsdk "github.com/tjcelaya/go-datera/pkg/dsdk"
+)
+
+// FakeApiVersions are the API versions reported by a FakeServer
+var FakeApiVersions = []string{"v2", "v2.1", "v2.2"}
+
+// DefaultFakePageSize is the most entries a FakeServer returns per list
+// request when FakeServer.PageSize isn't set
+var DefaultFakePageSize = 100
+
+// DefaultFakeTenant is the tenant of requests without a tenant header
+const DefaultFakeTenant = "/root"
+
+// collections are the object collections a FakeServer keeps, with the id field
+// of their objects.  Snapshots are identified by their timestamp
+var collections = map[string]string{
+	"app_instances":     "name",
+	"storage_instances": "name",
+	"volumes":           "name",
+	"snapshots":         "timestamp",
+	"initiators":        "id",
+	"initiator_groups":  "name",
+	"storage_nodes":     "uuid",
+}
+
+// children are the collections nested in the objects of a collection, they
+// are embedded when the object is returned
+var children = map[string][]string{
+	"app_instances":     {"storage_instances", "snapshots"},
+	"storage_instances": {"volumes"},
+	"volumes":           {"snapshots"},
+}
+
+// tenantCollections are the top level collections kept per tenant, everything
+// else is shared by the whole cluster
+var tenantCollections = map[string]bool{
+	"app_instances":    true,
+	"initiators":       true,
+	"initiator_groups": true,
+}
+
+var versionPrefix = regexp.MustCompile(`^/v[0-9][0-9.]*(/|$)`)
+
+// Fault describes how a FakeServer misbehaves for matching requests
+type Fault struct {
+	// Method matches requests with this method, any method when ""
+	Method string
+	// Path matches requests for this path and its children, without the API
+	// version, eg. "app_instances" or "login".  Every request matches when ""
+	Path string
+	// Latency delays the response, or the handling of the request if neither
+	// Status nor Drop are set
+	Latency time.Duration
+	// Status is returned instead of handling the request, eg. 503
+	Status int
+	// RetryAfter is sent as the Retry-After header of a Status response
+	RetryAfter time.Duration
+	// Drop closes the connection without a response.  The http package
+	// resends idempotent requests dropped on a reused connection once, so the
+	// caller only sees an error for those if the resent request is dropped too
+	Drop bool
+	// Times is how many requests the fault applies to, every request when 0
+	Times int
+}
+
+type fault struct {
+	Fault
+	left int
+}
+
+// matches reports whether the fault applies to a request for p, the path
+// without the API version
+func (f *fault) matches(method, p string) bool {
+	if f.Method != "" && !strings.EqualFold(f.Method, method) {
+		return false
+	}
+	fp := strings.Trim(f.Path, "/")
+	return fp == "" || p == fp || strings.HasPrefix(p, fp+"/")
+}
+
+type fakeObject struct {
+	seq  int
+	kind string
+	data map[string]interface{}
+}
+
+// FakeServer is an in-memory Datera cluster serving the part of the REST API
+// used by the dsdk package.  It keeps the objects created through it, per
+// tenant where the cluster does, and paginates lists like the cluster
+//
+//	fs := dsdktest.NewFakeServer()
+//	defer fs.Close()
+//	sdk, err := dsdk.NewSDK(fs.Config(), false)
+//
+// Username, Password, SessionTTL and PageSize should be set before making
+// requests
+type FakeServer struct {
+	// Username and Password are the credentials accepted by login, "admin"
+	// and "password" by default
+	Username string
+	Password string
+	// SessionTTL is how long api keys are valid after the login, they don't
+	// expire when 0.  See ExpireSessions
+	SessionTTL time.Duration
+	// PageSize is the most entries returned per list request, defaults to
+	// DefaultFakePageSize
+	PageSize int
+
+	server   *httptest.Server
+	m        sync.Mutex
+	seq      int
+	objects  map[string]*fakeObject
+	sessions map[string]time.Time
+	logins   int
+	faults   []*fault
+	events   []interface{}
+	metrics  map[string][]interface{}
+	requests []RecordedRequest
+}
+
+// NewFakeServer starts a FakeServer with a system and three storage nodes.
+// It should be closed once done
+func NewFakeServer() *FakeServer {
+	s := &FakeServer{
+		Username: "admin",
+		Password: "password",
+		objects:  map[string]*fakeObject{},
+		sessions: map[string]time.Time{},
+		metrics:  map[string][]interface{}{},
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	addr := s.Addr()
+	host, _, _ := net.SplitHostPort(addr)
+	s.store("", "/system", "", map[string]interface{}{
+		"path":           "/system",
+		"name":           "fake-cluster",
+		"uuid":           s.uuid(),
+		"health":         "ok",
+		"op_state":       "running",
+		"sw_version":     "3.3.5",
+		"build_version":  "3.3.5-fake",
+		"total_capacity": 3 * 1024 * 1024,
+	})
+	s.store("", "/system/network/mgmt_vip", "", map[string]interface{}{
+		"path":          "/system/network/mgmt_vip",
+		"network_paths": []interface{}{map[string]interface{}{"ip": host}},
+	})
+	for i := 1; i <= 3; i++ {
+		uuid := s.uuid()
+		p := "/storage_nodes/" + uuid
+		s.store("", p, "storage_nodes", map[string]interface{}{
+			"path":           p,
+			"uuid":           uuid,
+			"name":           fmt.Sprintf("fake-node-%d", i),
+			"admin_state":    "online",
+			"op_state":       "running",
+			"health":         "ok",
+			"mgmt_ip_1":      addr,
+			"total_capacity": 1024 * 1024,
+		})
+	}
+	return s
+}
+
+// URL returns the base URL of the server, eg. http://127.0.0.1:34567
+func (s *FakeServer) URL() string {
+	return s.server.URL
+}
+
+// Addr returns the host and port of the server
+func (s *FakeServer) Addr() string {
+	return strings.TrimPrefix(s.server.URL, "http://")
+}
+
+// Config returns a configuration connecting to the server as DefaultFakeTenant
+// with API version 2.2.  The SDK must be created with secure set to false
+func (s *FakeServer) Config() *udc.UDC {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return &udc.UDC{
+		Username:   s.Username,
+		Password:   s.Password,
+		MgmtIp:     s.Addr(),
+		Tenant:     DefaultFakeTenant,
+		ApiVersion: "2.2",
+	}
+}
+
+// Close shuts the server down
+func (s *FakeServer) Close() {
+	s.server.CloseClientConnections()
+	s.server.Close()
+}
+
+// ExpireSessions invalidates every api key, so the next requests made with
+// them get a 401
+func (s *FakeServer) ExpireSessions() {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.sessions = map[string]time.Time{}
+}
+
+// Logins returns the number of successful logins
+func (s *FakeServer) Logins() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.logins
+}
+
+// Requests returns the requests received so far, faulted ones included
+func (s *FakeServer) Requests() []RecordedRequest {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return append([]RecordedRequest(nil), s.requests...)
+}
+
+// AddFault makes the server misbehave for the requests matching f.  Faults
+// apply in the order they were added, a request is affected by one at most
+func (s *FakeServer) AddFault(f Fault) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.faults = append(s.faults, &fault{Fault: f, left: f.Times})
+}
+
+// ClearFaults makes the server behave again
+func (s *FakeServer) ClearFaults() {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.faults = nil
+}
+
+// AddEvent adds a system event, returned by /events/system
+func (s *FakeServer) AddEvent(e *dsdk.SystemEvent) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.events = append(s.events, toJSONValue(e))
+}
+
+// SetMetrics sets what /metrics/<route> returns, eg. for route "io/reads" or
+// "hw/cpu_usage"
+func (s *FakeServer) SetMetrics(route string, metrics ...*dsdk.Metrics) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	list := make([]interface{}, 0, len(metrics))
+	for _, m := range metrics {
+		list = append(list, toJSONValue(m))
+	}
+	s.metrics[strings.Trim(route, "/")] = list
+}
+
+// Object returns the object at p as the API would, eg.
+// "/app_instances/my-ai".  tenant is ignored for objects that aren't kept per
+// tenant
+func (s *FakeServer) Object(tenant, p string) (map[string]interface{}, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	p = "/" + strings.Trim(p, "/")
+	scope := objectScope(tenant, p)
+	if _, ok := s.objects[scope+"|"+p]; !ok {
+		return nil, false
+	}
+	return s.render(scope, p), true
+}
+
+func (s *FakeServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	p := strings.Trim(versionPrefix.ReplaceAllString(r.URL.Path, "/"), "/")
+
+	s.m.Lock()
+	s.requests = append(s.requests, RecordedRequest{
+		Method:  r.Method,
+		URL:     requestURL(r.URL),
+		Headers: r.Header,
+		Body:    string(body),
+	})
+	f := s.fault(r.Method, p)
+	s.m.Unlock()
+
+	if f != nil {
+		if f.Latency > 0 {
+			select {
+			case <-time.After(f.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if f.Drop {
+			if hj, ok := w.(http.Hijacker); ok {
+				if conn, _, err := hj.Hijack(); err == nil {
+					conn.Close()
+					return
+				}
+			}
+			panic(http.ErrAbortHandler)
+		}
+		if f.Status != 0 {
+			if f.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int((f.RetryAfter+time.Second-1)/time.Second)))
+			}
+			writeError(w, f.Status, "FaultInjectedError", "fault injected for "+r.URL.Path)
+			return
+		}
+	}
+
+	if r.URL.Path == "/api_versions" {
+		writeJSON(w, http.StatusOK, &dsdk.ApiVersions{ApiVersions: FakeApiVersions})
+		return
+	}
+	version := strings.Trim(versionPrefix.FindString(r.URL.Path), "/")
+	if version == "" {
+		writeError(w, http.StatusNotFound, "NotFoundError", r.URL.Path+" not found")
+		return
+	}
+	if p == "login" {
+		s.login(w, r, body, version)
+		return
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	issued, ok := s.sessions[r.Header.Get("Auth-Token")]
+	if !ok || (s.SessionTTL > 0 && time.Since(issued) > s.SessionTTL) {
+		delete(s.sessions, r.Header.Get("Auth-Token"))
+		writeError(w, dsdk.PermissionDenied, "AuthFailedError", "the api key is invalid or expired")
+		return
+	}
+	tenant := r.Header.Get("tenant")
+	if tenant == "" {
+		tenant = DefaultFakeTenant
+	}
+	rs := &response{version: version, tenant: tenant}
+	switch {
+	case p == "events/system":
+		s.list(rs, r, s.events)
+	case strings.HasPrefix(p, "metrics/io/") || strings.HasPrefix(p, "metrics/hw/"):
+		s.list(rs, r, s.metrics[strings.TrimPrefix(p, "metrics/")])
+	default:
+		s.handleObject(rs, r, "/"+p, body)
+	}
+	rs.write(w)
+}
+
+// fault returns the fault applying to the request, if any
+func (s *FakeServer) fault(method, p string) *fault {
+	for i, f := range s.faults {
+		if !f.matches(method, p) {
+			continue
+		}
+		if f.Times > 0 {
+			f.left--
+			if f.left <= 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+func (s *FakeServer) login(w http.ResponseWriter, r *http.Request, body []byte, version string) {
+	if r.Method != "PUT" {
+		writeError(w, http.StatusMethodNotAllowed, "InvalidRequestError", "login requires a PUT")
+		return
+	}
+	form, _ := url.ParseQuery(string(body))
+	s.m.Lock()
+	defer s.m.Unlock()
+	if form.Get("name") != s.Username || form.Get("password") != s.Password {
+		writeError(w, dsdk.PermissionDenied, "AuthFailedError", "invalid credentials")
+		return
+	}
+	s.logins++
+	now := time.Now()
+	key := fmt.Sprintf("%s-%d", s.uuid(), s.logins)
+	s.sessions[key] = now
+	writeJSON(w, http.StatusOK, &dsdk.ApiLogin{Key: key, Version: version, ReqTime: int(now.Unix())})
+}
+
+// response is what a request handled with the lock held results in
+type response struct {
+	version string
+	tenant  string
+	status  int
+	body    interface{}
+}
+
+func (rs *response) error(status int, name, msg string) {
+	rs.status = status
+	rs.body = &dsdk.ApiErrorResponse{Name: name, Code: status, Http: status, Message: msg, Tenant: rs.tenant, Version: rs.version}
+}
+
+func (rs *response) object(p string, data map[string]interface{}) {
+	rs.status = http.StatusOK
+	rs.body = &dsdk.ApiOuter{Data: data, Version: rs.version, Tenant: rs.tenant, Path: p}
+}
+
+func (rs *response) write(w http.ResponseWriter) {
+	writeJSON(w, rs.status, rs.body)
+}
+
+// list writes the page of entries requested by the offset and limit params
+func (s *FakeServer) list(rs *response, r *http.Request, entries []interface{}) {
+	q := r.URL.Query()
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	pageSize := s.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultFakePageSize
+	}
+	if limit <= 0 || limit > pageSize {
+		limit = pageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	page := []interface{}{}
+	if offset < len(entries) {
+		end := offset + limit
+		if end > len(entries) {
+			end = len(entries)
+		}
+		page = append(page, entries[offset:end]...)
+	}
+	rs.status = http.StatusOK
+	rs.body = &dsdk.ApiListOuter{
+		Data:    page,
+		Version: rs.version,
+		Tenant:  rs.tenant,
+		Path:    r.URL.Path,
+		Metadata: map[string]interface{}{
+			"total_count": len(entries),
+			"offset":      offset,
+			"limit":       limit,
+		},
+	}
+}
+
+// handleObject serves the collections and objects kept by the server
+func (s *FakeServer) handleObject(rs *response, r *http.Request, p string, body []byte) {
+	scope := objectScope(rs.tenant, p)
+	kind := path.Base(p)
+	if _, ok := collections[kind]; ok {
+		parent := path.Dir(p)
+		if parent == "/" {
+			if kind == "storage_instances" || kind == "volumes" || kind == "snapshots" {
+				rs.error(http.StatusNotFound, "NotFoundError", p+" not found")
+				return
+			}
+		} else {
+			po, ok := s.objects[scope+"|"+parent]
+			if !ok || !isChild(po.kind, kind) {
+				rs.error(http.StatusNotFound, "NotFoundError", p+" not found")
+				return
+			}
+		}
+		switch r.Method {
+		case "GET":
+			entries := []interface{}{}
+			for _, cp := range s.childPaths(scope, p) {
+				entries = append(entries, s.render(scope, cp))
+			}
+			s.list(rs, r, entries)
+		case "POST":
+			if kind == "storage_nodes" {
+				rs.error(http.StatusMethodNotAllowed, "InvalidRequestError", "storage nodes can't be created")
+				return
+			}
+			req := map[string]interface{}{}
+			if err := json.Unmarshal(body, &req); err != nil {
+				rs.error(http.StatusBadRequest, "ValidationError", "invalid JSON body: "+err.Error())
+				return
+			}
+			s.create(rs, scope, p, req)
+		default:
+			rs.error(http.StatusMethodNotAllowed, "InvalidRequestError", r.Method+" not allowed on "+p)
+		}
+		return
+	}
+
+	o, ok := s.objects[scope+"|"+p]
+	if !ok {
+		rs.error(http.StatusNotFound, "NotFoundError", p+" not found")
+		return
+	}
+	switch r.Method {
+	case "GET":
+		rs.object(p, s.render(scope, p))
+	case "PUT":
+		req := map[string]interface{}{}
+		if err := json.Unmarshal(body, &req); err != nil {
+			rs.error(http.StatusBadRequest, "ValidationError", "invalid JSON body: "+err.Error())
+			return
+		}
+		for k, v := range req {
+			if k == "path" || k == collections[o.kind] || isChild(o.kind, k) {
+				continue
+			}
+			o.data[k] = v
+		}
+		rs.object(p, s.render(scope, p))
+	case "DELETE":
+		if o.kind == "" || o.kind == "storage_nodes" {
+			rs.error(http.StatusMethodNotAllowed, "InvalidRequestError", p+" can't be deleted")
+			return
+		}
+		data := s.render(scope, p)
+		for k := range s.objects {
+			if k == scope+"|"+p || strings.HasPrefix(k, scope+"|"+p+"/") {
+				delete(s.objects, k)
+			}
+		}
+		rs.object(p, data)
+	default:
+		rs.error(http.StatusMethodNotAllowed, "InvalidRequestError", r.Method+" not allowed on "+p)
+	}
+}
+
+// create adds the object described by req to the collection at p, along with
+// the children it describes
+func (s *FakeServer) create(rs *response, scope, p string, req map[string]interface{}) {
+	kind := path.Base(p)
+	idField := collections[kind]
+	id, _ := req[idField].(string)
+	if kind == "snapshots" {
+		id = fmt.Sprintf("%d.%09d", time.Now().Unix(), s.seq+1)
+	}
+	if id == "" || strings.Contains(id, "/") {
+		rs.error(http.StatusBadRequest, "ValidationError", fmt.Sprintf("a valid %s is required", idField))
+		return
+	}
+	op := p + "/" + id
+	if _, ok := s.objects[scope+"|"+op]; ok {
+		rs.error(dsdk.Conflict, "ConflictError", op+" already exists")
+		return
+	}
+	s.createObject(scope, op, kind, id, req)
+	rs.object(op, s.render(scope, op))
+}
+
+func (s *FakeServer) createObject(scope, p, kind, id string, req map[string]interface{}) {
+	data := map[string]interface{}{}
+	for k, v := range req {
+		if !isChild(kind, k) {
+			data[k] = v
+		}
+	}
+	data["path"] = p
+	data[collections[kind]] = id
+	if _, ok := data["uuid"]; !ok {
+		data["uuid"] = s.uuid()
+	}
+	if scope != "" {
+		data["tenant"] = scope
+	}
+	switch kind {
+	case "app_instances":
+		data["id"] = id
+		setDefaults(data, "admin_state", "online", "op_state", "available", "health", "ok")
+	case "storage_instances":
+		setDefaults(data, "admin_state", "online", "op_state", "available", "health", "ok")
+		data["access"] = map[string]interface{}{
+			"iqn": "iqn.2013-05.com.daterainc:tc:01:sn:" + strings.Replace(data["uuid"].(string), "-", "", -1)[:16],
+			"ips": []interface{}{"172.28.41.10"},
+		}
+		delete(data, "acl_policy")
+		s.store(scope, p+"/acl_policy", "", map[string]interface{}{
+			"path":             p + "/acl_policy",
+			"initiators":       []interface{}{},
+			"initiator_groups": []interface{}{},
+		})
+	case "volumes":
+		setDefaults(data, "replica_count", float64(3), "op_state", "available", "health", "ok")
+	case "snapshots":
+		data["utc_ts"] = id
+		setDefaults(data, "op_state", "available")
+	case "initiator_groups":
+		setDefaults(data, "members", []interface{}{})
+	}
+	s.store(scope, p, kind, data)
+
+	// eg. the storage_instances and volumes of an app_instance created in
+	// one go
+	for _, child := range children[kind] {
+		list, _ := req[child].([]interface{})
+		for _, c := range list {
+			creq, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			cid, _ := creq[collections[child]].(string)
+			if child == "snapshots" || cid == "" {
+				continue
+			}
+			s.createObject(scope, p+"/"+child+"/"+cid, child, cid, creq)
+		}
+	}
+}
+
+func (s *FakeServer) store(scope, p, kind string, data map[string]interface{}) {
+	s.seq++
+	s.objects[scope+"|"+p] = &fakeObject{seq: s.seq, kind: kind, data: data}
+}
+
+// render returns a copy of the object at p with its children embedded
+func (s *FakeServer) render(scope, p string) map[string]interface{} {
+	o := s.objects[scope+"|"+p]
+	data := make(map[string]interface{}, len(o.data))
+	for k, v := range o.data {
+		data[k] = v
+	}
+	data = toJSONValue(data).(map[string]interface{})
+	for _, child := range children[o.kind] {
+		list := []interface{}{}
+		for _, cp := range s.childPaths(scope, p+"/"+child) {
+			list = append(list, s.render(scope, cp))
+		}
+		data[child] = list
+	}
+	if _, ok := s.objects[scope+"|"+p+"/acl_policy"]; ok {
+		data["acl_policy"] = s.render(scope, p+"/acl_policy")
+	}
+	return data
+}
+
+// childPaths returns the paths of the objects in the collection at p in the
+// order they were created
+func (s *FakeServer) childPaths(scope, p string) []string {
+	kind := path.Base(p)
+	prefix := scope + "|" + p + "/"
+	objs := []*fakeObject{}
+	for k, o := range s.objects {
+		if o.kind == kind && strings.HasPrefix(k, prefix) && !strings.Contains(k[len(prefix):], "/") {
+			objs = append(objs, o)
+		}
+	}
+	sort.Slice(objs, func(i, j int) bool { return objs[i].seq < objs[j].seq })
+	paths := make([]string, len(objs))
+	for i, o := range objs {
+		paths[i] = o.data["path"].(string)
+	}
+	return paths
+}
+
+func (s *FakeServer) uuid() string {
+	s.seq++
+	return fmt.Sprintf("%08x-fa4e-4000-8000-%012x", s.seq, s.seq)
+}
+
+// objectScope returns the tenant the object at p is kept in, "" for objects
+// shared by the whole cluster
+func objectScope(tenant, p string) string {
+	top := strings.SplitN(strings.Trim(p, "/"), "/", 2)[0]
+	if tenantCollections[top] {
+		return tenant
+	}
+	return ""
+}
+
+func isChild(kind, child string) bool {
+	for _, c := range children[kind] {
+		if c == child {
+			return true
+		}
+	}
+	return false
+}
+
+func setDefaults(data map[string]interface{}, kv ...interface{}) {
+	for i := 0; i+1 < len(kv); i += 2 {
+		k := kv[i].(string)
+		if _, ok := data[k]; !ok {
+			data[k] = kv[i+1]
+		}
+	}
+}
+
+// toJSONValue returns v as decoded from its JSON, so what the server returns
+// can't be modified through values it was given
+func toJSONValue(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var r interface{}
+	json.Unmarshal(data, &r)
+	return r
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, name, msg string) {
+	writeJSON(w, status, &dsdk.ApiErrorResponse{Name: name, Code: status, Http: status, Message: msg})
+}
@@ -0,0 +1,151 @@
+package dsdktest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	dsdk "github.com/tjcelaya/go-datera/pkg/dsdk"
+)
+
+func countRequests(fs *FakeServer, method, url string) int {
+	n := 0
+	for _, r := range fs.Requests() {
+		if r.Method == method && r.URL == url {
+			n++
+		}
+	}
+	return n
+}
+
+func TestFakeServer(t *testing.T) {
+	fs := NewFakeServer()
+	defer fs.Close()
+	fs.PageSize = 2
+	sdk, err := dsdk.NewSDK(fs.Config(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctxt := sdk.NewContext()
+
+	ai, err := sdk.AppInstances.CreateE(&dsdk.AppInstancesCreateRequest{
+		Ctxt: ctxt,
+		Name: "my-ai",
+		StorageInstances: []*dsdk.StorageInstance{{
+			Name:    "si-1",
+			Volumes: []*dsdk.Volume{{Name: "vol-1", Size: 5}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ai.StorageInstances) != 1 || len(ai.StorageInstances[0].Volumes) != 1 {
+		t.Fatalf("unexpected app_instance %#v", ai)
+	}
+	si := ai.StorageInstances[0]
+	vol := si.Volumes[0]
+	if vol.Path != "/app_instances/my-ai/storage_instances/si-1/volumes/vol-1" || vol.ReplicaCount != 3 || vol.Size != 5 {
+		t.Errorf("unexpected volume %#v", vol)
+	}
+	if _, err = sdk.AppInstances.CreateE(&dsdk.AppInstancesCreateRequest{Ctxt: ctxt, Name: "my-ai"}); !errors.Is(err, dsdk.ErrConflict) {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+
+	// pagination
+	for _, iqn := range []string{"iqn.1993-08.org.debian:01:a", "iqn.1993-08.org.debian:01:b", "iqn.1993-08.org.debian:01:c"} {
+		if _, err = sdk.Initiators.CreateE(&dsdk.InitiatorsCreateRequest{Ctxt: ctxt, Id: iqn, Name: iqn[len(iqn)-1:]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	inits, err := sdk.Initiators.ListE(&dsdk.InitiatorsListRequest{Ctxt: ctxt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inits) != 3 || inits[2].Name != "c" {
+		t.Errorf("unexpected initiators %#v", inits)
+	}
+	if n := countRequests(fs, "GET", "/v2.2/initiators?offset=2"); n != 1 {
+		t.Errorf("expected the second page to be fetched once, got %d", n)
+	}
+
+	// nested objects
+	if _, err = si.AclPolicy.SetE(&dsdk.AclPolicySetRequest{Ctxt: ctxt, Initiators: inits[:1]}); err != nil {
+		t.Fatal(err)
+	}
+	acl, err := si.AclPolicy.GetE(&dsdk.AclPolicyGetRequest{Ctxt: ctxt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(acl.Initiators) != 1 || acl.Initiators[0].Path != "/initiators/iqn.1993-08.org.debian:01:a" {
+		t.Errorf("unexpected acl_policy %#v", acl)
+	}
+	if _, err = vol.SnapshotsEp.CreateE(&dsdk.SnapshotsCreateRequest{Ctxt: ctxt}); err != nil {
+		t.Fatal(err)
+	}
+	snaps, err := vol.SnapshotsEp.ListE(&dsdk.SnapshotsListRequest{Ctxt: ctxt})
+	if err != nil || len(snaps) != 1 || snaps[0].UtcTs == "" {
+		t.Errorf("unexpected snapshots %#v, %v", snaps, err)
+	}
+
+	// tenants
+	other := dsdk.WithTenant(ctxt, "/root/other")
+	if ais, err := sdk.AppInstances.ListE(&dsdk.AppInstancesListRequest{Ctxt: other}); err != nil || len(ais) != 0 {
+		t.Errorf("expected no app_instances in another tenant, got %d, %v", len(ais), err)
+	}
+	if _, ok := fs.Object("/root/other", "/app_instances/my-ai"); ok {
+		t.Errorf("app_instance leaked into another tenant")
+	}
+
+	// expired sessions are refreshed by the SDK
+	fs.ExpireSessions()
+	if _, err = sdk.System.GetE(&dsdk.SystemGetRequest{Ctxt: ctxt}); err != nil {
+		t.Fatal(err)
+	}
+	if fs.Logins() != 2 {
+		t.Errorf("expected a second login, got %d", fs.Logins())
+	}
+
+	// faults
+	fs.AddFault(Fault{Path: "system", Status: 503, Times: 1})
+	if _, err = sdk.System.GetE(&dsdk.SystemGetRequest{Ctxt: ctxt}); err != nil {
+		t.Errorf("expected the 503 to be retried, got %v", err)
+	}
+	fs.AddFault(Fault{Method: "POST", Path: "initiators", Drop: true, Times: 1})
+	if _, err = sdk.Initiators.CreateE(&dsdk.InitiatorsCreateRequest{Ctxt: ctxt, Id: "iqn.1993-08.org.debian:01:d", Name: "d"}); err == nil {
+		t.Errorf("expected the dropped connection to fail the request")
+	}
+	if _, err = sdk.Initiators.CreateE(&dsdk.InitiatorsCreateRequest{Ctxt: ctxt, Id: "iqn.1993-08.org.debian:01:d", Name: "d"}); err != nil {
+		t.Errorf("expected the create to succeed once the fault is over, got %v", err)
+	}
+	nodes, err := sdk.StorageNodes.ListE(&dsdk.StorageNodesListRequest{Ctxt: ctxt})
+	if err != nil || len(nodes) != 3 {
+		t.Errorf("expected 3 storage nodes, got %d, %v", len(nodes), err)
+	}
+	fs.AddFault(Fault{Latency: time.Second})
+	tctxt, cancel := context.WithTimeout(ctxt, 50*time.Millisecond)
+	defer cancel()
+	if _, err = sdk.System.GetE(&dsdk.SystemGetRequest{Ctxt: tctxt}); err == nil {
+		t.Errorf("expected the request to time out")
+	}
+	fs.ClearFaults()
+
+	// events and metrics
+	fs.AddEvent(&dsdk.SystemEvent{Code: "VolumeDegraded", Severity: "warning"})
+	events, err := sdk.SystemEvents.ListE(&dsdk.SystemEventsRequest{Ctxt: ctxt})
+	if err != nil || len(events) != 1 || events[0].Code != "VolumeDegraded" {
+		t.Errorf("unexpected events %#v, %v", events, err)
+	}
+	fs.SetMetrics("io/reads", &dsdk.Metrics{EntityPath: vol.Path, Points: []dsdk.Point{{Time: 1, Value: 42}}})
+	metrics, err := sdk.IOMetrics.ListE(&dsdk.IOMetricsRequest{Ctxt: ctxt, Type: dsdk.Reads})
+	if err != nil || len(metrics) != 1 || metrics[0].Points[0].Value != 42 {
+		t.Errorf("unexpected metrics %#v, %v", metrics, err)
+	}
+
+	if _, err = ai.DeleteE(&dsdk.AppInstanceDeleteRequest{Ctxt: ctxt}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fs.Object(DefaultFakeTenant, vol.Path); ok {
+		t.Errorf("volume survived the deletion of its app_instance")
+	}
+}
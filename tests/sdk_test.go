@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"github.com/Datera/go-udc/pkg/udc"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	greq "github.com/levigross/grequests"
 	"github.com/sirupsen/logrus"
 	dsdk "github.com/tjcelaya/go-datera/pkg/dsdk"
 	"gopkg.in/h2non/gock.v1"
@@ -353,7 +355,8 @@ func TestRetryScenarios(t *testing.T) {
 					JSON(testApiResponse)
 			},
 			expected: expected{
-				Err: dsdk.ErrRetryTimeout,
+				ApiErr: &dsdk.ApiErrorResponse{Message: "overloaded", Http: 503},
+				Err:    dsdk.ErrRetryTimeout,
 			},
 		},
 		{
@@ -496,6 +499,1057 @@ func TestRetryScenarios(t *testing.T) {
 	}
 }
 
+func TestRetryPolicies(t *testing.T) {
+	testApiResponse := dsdk.ApiOuter{Data: map[string]interface{}{"name": "the system"}}
+	overloaded := &dsdk.ApiErrorResponse{Message: "overloaded", Http: 503}
+
+	testCases := []struct {
+		desc      string
+		policy    dsdk.RetryPolicy
+		perReq    bool
+		setup     func()
+		expectErr *dsdk.ApiErrorResponse
+	}{
+		{
+			desc:   "no retry policy returns the first 503",
+			policy: dsdk.NoRetryPolicy,
+			setup: func() {
+				gock.New("http://127.0.0.1:7717").
+					Get("/v1/system").
+					Reply(503).
+					JSON(overloaded)
+			},
+			expectErr: overloaded,
+		},
+		{
+			desc:   "per request policy overrides the connection policy",
+			policy: dsdk.NoRetryPolicy,
+			perReq: true,
+			setup: func() {
+				gock.New("http://127.0.0.1:7717").
+					Get("/v1/system").
+					Reply(503).
+					JSON(overloaded)
+			},
+			expectErr: overloaded,
+		},
+		{
+			desc: "custom statuses are retried honoring Retry-After",
+			policy: &dsdk.ExponentialBackoffPolicy{
+				Statuses:        []int{500},
+				InitialInterval: time.Hour,
+				MaxElapsed:      5 * time.Second,
+			},
+			setup: func() {
+				gock.New("http://127.0.0.1:7717").
+					Get("/v1/system").
+					Reply(500).
+					SetHeader("Retry-After", "1").
+					JSON(&dsdk.ApiErrorResponse{Message: "goofed"})
+
+				gock.New("http://127.0.0.1:7717").
+					Get("/v1/system").
+					Reply(200).
+					JSON(testApiResponse)
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			defer gock.OffAll()
+			gock.New("http://127.0.0.1:7717").
+				Put("/v1/login").
+				Reply(200).
+				JSON(&dsdk.ApiLogin{Key: "thekey"})
+			tC.setup()
+
+			sdk, err := dsdk.NewSDK(&udc.UDC{
+				MgmtIp:     "127.0.0.1",
+				Username:   "foo",
+				Password:   "bar",
+				ApiVersion: "1",
+			}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctxt := sdk.NewContext()
+			if tC.perReq {
+				ctxt = dsdk.WithRetryPolicy(ctxt, tC.policy)
+			} else {
+				sdk.Conn.SetRetryPolicy(tC.policy)
+			}
+			_, aer, err := sdk.System.Get(&dsdk.SystemGetRequest{
+				Ctxt: ctxt,
+			})
+			assert.NilError(t, err)
+			if tC.expectErr == nil {
+				assert.Assert(t, aer == nil, "unexpected api error %+v", aer)
+			} else {
+				assert.Assert(t, aer != nil)
+				assert.Equal(t, aer.Http, tC.expectErr.Http)
+			}
+			assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+		})
+	}
+}
+
+func TestRetryCancellation(t *testing.T) {
+	defer gock.OffAll()
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/login").
+		Reply(200).
+		JSON(&dsdk.ApiLogin{Key: "thekey"})
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/system").
+		Persist().
+		Reply(503).
+		JSON(&dsdk.ApiErrorResponse{Message: "overloaded"})
+
+	sdk, err := dsdk.NewSDK(&udc.UDC{
+		MgmtIp:     "127.0.0.1",
+		Username:   "foo",
+		Password:   "bar",
+		ApiVersion: "1",
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctxt, cancel := context.WithTimeout(sdk.NewContext(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, aer, err := sdk.System.Get(&dsdk.SystemGetRequest{
+		Ctxt: ctxt,
+	})
+	assert.Assert(t, aer == nil)
+	assert.Equal(t, err, context.DeadlineExceeded)
+	assert.Assert(t, time.Since(start) < 2*time.Second, "retries were not cancelled promptly")
+}
+
+func TestEndpointFailover(t *testing.T) {
+	defer gock.OffAll()
+	gock.New("http://127.0.0.2:7717").
+		Put("/v1/login").
+		ReplyError(errors.New("connect: connection refused"))
+	gock.New("http://127.0.0.1:8000").
+		Put("/v1/login").
+		Reply(200).
+		JSON(&dsdk.ApiLogin{Key: "thekey"})
+	gock.New("http://127.0.0.1:8000").
+		Get("/v1/system").
+		Reply(200).
+		JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "the system"}})
+
+	sdk, err := dsdk.NewSDK(&udc.UDC{
+		MgmtIp:     "127.0.0.2",
+		Username:   "foo",
+		Password:   "bar",
+		ApiVersion: "1",
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NilError(t, sdk.Conn.SetEndpoints(dsdk.EndpointConfig{
+		Hosts: []string{"127.0.0.2", "127.0.0.1:8000"},
+	}))
+	assert.DeepEqual(t, sdk.Conn.Endpoints(), []string{"127.0.0.2:7717", "127.0.0.1:8000"})
+
+	// the first endpoint fails to connect during login, the second one should be
+	// used for the login and all following requests while the first one cools down
+	s, err := sdk.System.GetE(&dsdk.SystemGetRequest{
+		Ctxt: sdk.NewContext(),
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, s.Name, "the system")
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+}
+
+func TestInterceptors(t *testing.T) {
+	defer gock.OffAll()
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/login").
+		MatchHeader("X-Audit", "yes").
+		Reply(200).
+		JSON(&dsdk.ApiLogin{Key: "thekey"})
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/app_instances/my-ai").
+		MatchHeader("X-Audit", "yes").
+		Reply(200).
+		JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "my-ai"}})
+
+	sdk, err := dsdk.NewSDK(&udc.UDC{
+		MgmtIp:     "127.0.0.1",
+		Username:   "foo",
+		Password:   "bar",
+		ApiVersion: "1",
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := []string{}
+	injected := false
+	sdk.Conn.AddInterceptor(
+		// audit every call
+		func(call *dsdk.Call, next dsdk.Invoker) error {
+			call.Header.Set("X-Audit", "yes")
+			err := next(call)
+			routes = append(routes, fmt.Sprintf("%s %s %d", call.Method, call.Route, call.StatusCode))
+			return err
+		},
+		// fail the first request to the app instance without sending it
+		func(call *dsdk.Call, next dsdk.Invoker) error {
+			if call.Route == "/v1/app_instances/:id" && !injected {
+				injected = true
+				call.StatusCode = 503
+				call.ResponseBody = []byte(`{"message": "injected"}`)
+				return nil
+			}
+			return next(call)
+		},
+	)
+
+	ai, err := sdk.AppInstances.GetE(&dsdk.AppInstancesGetRequest{
+		Ctxt: sdk.NewContext(),
+		Id:   "my-ai",
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, ai.Name, "my-ai")
+	assert.DeepEqual(t, routes, []string{
+		"PUT /v1/login 200",
+		"GET /v1/app_instances/:id 503",
+		"GET /v1/app_instances/:id 200",
+	})
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+}
+
+type testSpan struct {
+	name  string
+	attrs map[string]interface{}
+	err   error
+	ended bool
+}
+
+func (s *testSpan) SetAttribute(k string, v interface{}) { s.attrs[k] = v }
+func (s *testSpan) RecordError(err error)                { s.err = err }
+func (s *testSpan) End()                                 { s.ended = true }
+
+type testTracer struct {
+	m     sync.Mutex
+	spans []*testSpan
+}
+
+func (tr *testTracer) Start(ctxt context.Context, name string) (context.Context, dsdk.Span) {
+	tr.m.Lock()
+	defer tr.m.Unlock()
+	s := &testSpan{name: name, attrs: map[string]interface{}{}}
+	tr.spans = append(tr.spans, s)
+	return ctxt, s
+}
+
+func (tr *testTracer) Inject(ctxt context.Context, h http.Header) {
+	h.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+}
+
+func TestTracing(t *testing.T) {
+	defer gock.OffAll()
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/login").
+		MatchHeader("traceparent", "^00-0af7651916cd43dd8448eb211c80319c").
+		Reply(200).
+		JSON(&dsdk.ApiLogin{Key: "thekey"})
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/system").
+		Reply(503).
+		JSON(&dsdk.ApiErrorResponse{Message: "overloaded", Id: 42})
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/system").
+		MatchHeader("traceparent", "^00-0af7651916cd43dd8448eb211c80319c").
+		Reply(200).
+		JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "the system"}})
+
+	sdk, err := dsdk.NewSDK(&udc.UDC{
+		MgmtIp:     "127.0.0.1",
+		Username:   "foo",
+		Password:   "bar",
+		ApiVersion: "1",
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := &testTracer{}
+	sdk.Conn.SetTracer(tracer)
+
+	_, err = sdk.System.GetE(&dsdk.SystemGetRequest{Ctxt: sdk.NewContext()})
+	assert.NilError(t, err)
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+
+	assert.Equal(t, len(tracer.spans), 3)
+	login, failed, ok := tracer.spans[0], tracer.spans[1], tracer.spans[2]
+	assert.Equal(t, login.name, "/v1/login")
+	assert.Equal(t, failed.name, "/v1/system")
+	assert.Equal(t, failed.attrs[dsdk.SpanAttrStatusCode], 503)
+	assert.Equal(t, failed.attrs[dsdk.SpanAttrApiReqId], 42)
+	assert.Equal(t, failed.attrs[dsdk.SpanAttrRetryAttempt], 1)
+	assert.Assert(t, errors.Is(failed.err, dsdk.ErrUnavailable))
+	assert.Equal(t, ok.attrs[dsdk.SpanAttrStatusCode], 200)
+	assert.Equal(t, ok.attrs[dsdk.SpanAttrRetryAttempt], 2)
+	assert.Assert(t, login.ended && failed.ended && ok.ended)
+}
+
+func TestMetrics(t *testing.T) {
+	defer gock.OffAll()
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/login").
+		Times(2).
+		Reply(200).
+		JSON(&dsdk.ApiLogin{Key: "thekey"})
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/system").
+		Reply(503).
+		JSON(&dsdk.ApiErrorResponse{Message: "overloaded"})
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/system").
+		Reply(200).
+		JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "the system"}})
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/app_instances/my-ai").
+		Reply(401).
+		JSON(&dsdk.ApiErrorResponse{Message: "session expired"})
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/app_instances/my-ai").
+		Reply(200).
+		JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "my-ai"}})
+
+	sdk, err := dsdk.NewSDK(&udc.UDC{
+		MgmtIp:     "127.0.0.1",
+		Username:   "foo",
+		Password:   "bar",
+		ApiVersion: "1",
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := dsdk.NewClientMetrics("")
+	sdk.Conn.SetMetrics(metrics)
+
+	_, err = sdk.System.GetE(&dsdk.SystemGetRequest{Ctxt: sdk.NewContext()})
+	assert.NilError(t, err)
+	_, err = sdk.AppInstances.GetE(&dsdk.AppInstancesGetRequest{Ctxt: sdk.NewContext(), Id: "my-ai"})
+	assert.NilError(t, err)
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+
+	buf := &strings.Builder{}
+	_, err = metrics.WriteTo(buf)
+	assert.NilError(t, err)
+	out := buf.String()
+	for _, line := range []string{
+		`datera_sdk_request_duration_seconds_count{method="GET",route="/v1/system"} 2`,
+		`datera_sdk_request_duration_seconds_count{method="PUT",route="/v1/login"} 2`,
+		`datera_sdk_responses_total{method="GET",route="/v1/system",code="503"} 1`,
+		`datera_sdk_responses_total{method="GET",route="/v1/system",code="200"} 1`,
+		`datera_sdk_responses_total{method="GET",route="/v1/app_instances/:id",code="401"} 1`,
+		`datera_sdk_retries_total{method="GET",route="/v1/system"} 1`,
+		`datera_sdk_reauthentications_total 1`,
+		`datera_sdk_logins_total{result="success"} 2`,
+		`datera_sdk_requests_in_flight 0`,
+	} {
+		assert.Assert(t, strings.Contains(out, line+"\n"), "missing %q in\n%s", line, out)
+	}
+}
+
+func TestApiVersionNegotiation(t *testing.T) {
+	defer gock.OffAll()
+	gock.New("http://127.0.0.1:7717").
+		Get("/api_versions").
+		Reply(200).
+		JSON(&dsdk.ApiVersions{ApiVersions: []string{"v2", "v2.1", "v2.2", "v9.9"}})
+	gock.New("http://127.0.0.1:7717").
+		Put("/v2.2/login").
+		Reply(200).
+		JSON(&dsdk.ApiLogin{Key: "thekey"})
+	gock.New("http://127.0.0.1:7717").
+		Get("/v2.2/system").
+		Reply(200).
+		JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "the system"}})
+
+	sdk, err := dsdk.NewSDK(&udc.UDC{
+		MgmtIp:     "127.0.0.1",
+		Username:   "foo",
+		Password:   "bar",
+		ApiVersion: dsdk.ApiVersionAuto,
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, sdk.ApiVersion(), "2.2")
+	assert.Assert(t, sdk.Conn.ApiVersionAtLeast("2.1"))
+	assert.Assert(t, errors.Is(sdk.Conn.RequireApiVersion("3", "something new"), dsdk.ErrUnsupportedApiVersion))
+
+	_, err = sdk.System.GetE(&dsdk.SystemGetRequest{Ctxt: sdk.NewContext()})
+	assert.NilError(t, err)
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+
+	gock.New("http://127.0.0.1:7717").
+		Get("/api_versions").
+		Reply(200).
+		JSON(&dsdk.ApiVersions{ApiVersions: []string{"v1"}})
+	_, err = dsdk.NewSDK(&udc.UDC{
+		MgmtIp:   "127.0.0.1",
+		Username: "foo",
+		Password: "bar",
+	}, false)
+	assert.Assert(t, errors.Is(err, dsdk.ErrUnsupportedApiVersion))
+}
+
+type memTokenStore struct {
+	m      sync.Mutex
+	tokens map[string]string
+}
+
+func (s *memTokenStore) Load(ctxt context.Context, key string) (string, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.tokens[key], nil
+}
+
+func (s *memTokenStore) Store(ctxt context.Context, key, token string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.tokens[key] = token
+	return nil
+}
+
+func (s *memTokenStore) Delete(ctxt context.Context, key string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	delete(s.tokens, key)
+	return nil
+}
+
+func TestCredentialsAndTokenStore(t *testing.T) {
+	defer gock.OffAll()
+	// only one login for both SDKs, with the credentials from the provider
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/login").
+		BodyString(`name=rotated`).
+		Reply(200).
+		JSON(&dsdk.ApiLogin{Key: "key1"})
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/system").
+		MatchHeader("Auth-Token", "key1").
+		Times(2).
+		Reply(200).
+		JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "the system"}})
+
+	store := &memTokenStore{tokens: map[string]string{}}
+	newSDK := func() *dsdk.SDK {
+		sdk, err := dsdk.NewSDK(&udc.UDC{
+			MgmtIp:     "127.0.0.1",
+			Username:   "foo",
+			Password:   "bar",
+			ApiVersion: "1",
+		}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sdk.Conn.SetCredentialProvider(dsdk.StaticCredentialProvider{Username: "rotated", Password: "secret"})
+		sdk.Conn.SetTokenStore(store)
+		return sdk
+	}
+
+	for i := 0; i < 2; i++ {
+		sdk := newSDK()
+		_, err := sdk.System.GetE(&dsdk.SystemGetRequest{Ctxt: sdk.NewContext()})
+		assert.NilError(t, err)
+	}
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+	assert.Equal(t, len(store.tokens), 1)
+
+	// a stale stored token is dropped and replaced after a 401
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/system").
+		MatchHeader("Auth-Token", "key1").
+		Reply(401).
+		JSON(&dsdk.ApiErrorResponse{Message: "session expired"})
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/login").
+		Reply(200).
+		JSON(&dsdk.ApiLogin{Key: "key2"})
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/system").
+		MatchHeader("Auth-Token", "key2").
+		Reply(200).
+		JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "the system"}})
+	sdk := newSDK()
+	_, err := sdk.System.GetE(&dsdk.SystemGetRequest{Ctxt: sdk.NewContext()})
+	assert.NilError(t, err)
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+	for _, tok := range store.tokens {
+		assert.Equal(t, tok, "key2")
+	}
+}
+
+func TestSessionRefresh(t *testing.T) {
+	defer gock.OffAll()
+	newSDK := func() *dsdk.SDK {
+		sdk, err := dsdk.NewSDK(&udc.UDC{
+			MgmtIp:     "127.0.0.1",
+			Username:   "foo",
+			Password:   "bar",
+			ApiVersion: "1",
+		}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sdk
+	}
+	system := func(key string, times int) {
+		gock.New("http://127.0.0.1:7717").
+			Get("/v1/system").
+			MatchHeader("Auth-Token", key).
+			Times(times).
+			Reply(200).
+			JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "the system"}})
+	}
+	login := func(key string) *gock.Request {
+		r := gock.New("http://127.0.0.1:7717").Put("/v1/login")
+		r.Reply(200).JSON(&dsdk.ApiLogin{Key: key})
+		return r
+	}
+
+	// 100 requests failing with an expired session cause a single login
+	login("key1")
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/system").
+		MatchHeader("Auth-Token", "key1").
+		Persist().
+		Reply(401).
+		JSON(&dsdk.ApiErrorResponse{Message: "session expired"})
+	sdk := newSDK()
+	_, err := sdk.Conn.Login(sdk.NewContext())
+	assert.NilError(t, err)
+	login("key2")
+	system("key2", 100)
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := sdk.System.GetE(&dsdk.SystemGetRequest{Ctxt: sdk.NewContext()})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NilError(t, err)
+	}
+	assert.Assert(t, !gock.HasUnmatchedRequest())
+	gock.OffAll()
+
+	// an expired session is replaced before the request is made
+	login("key1")
+	system("key1", 1)
+	sdk = newSDK()
+	sdk.Conn.SetSessionConfig(dsdk.SessionConfig{TTL: 100 * time.Millisecond, NoBackgroundRefresh: true})
+	_, err = sdk.System.GetE(&dsdk.SystemGetRequest{Ctxt: sdk.NewContext()})
+	assert.NilError(t, err)
+	assert.Assert(t, !sdk.Conn.SessionExpires().IsZero())
+	time.Sleep(150 * time.Millisecond)
+	login("key2")
+	system("key2", 1)
+	_, err = sdk.System.GetE(&dsdk.SystemGetRequest{Ctxt: sdk.NewContext()})
+	assert.NilError(t, err)
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+	gock.OffAll()
+
+	// sessions in use are refreshed in the background, and can be ended on the cluster
+	login("key1")
+	system("key1", 1)
+	sdk = newSDK()
+	sdk.Conn.SetSessionConfig(dsdk.SessionConfig{TTL: time.Second, RefreshBefore: 800 * time.Millisecond})
+	_, err = sdk.System.GetE(&dsdk.SystemGetRequest{Ctxt: sdk.NewContext()})
+	assert.NilError(t, err)
+	login("key2")
+	expires := sdk.Conn.SessionExpires()
+	time.Sleep(400 * time.Millisecond)
+	assert.Assert(t, gock.IsDone(), "session was not refreshed in the background")
+	assert.Assert(t, sdk.Conn.SessionExpires().After(expires))
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/logout").
+		MatchHeader("Auth-Token", "key2").
+		Reply(200).
+		JSON(dsdk.ApiOuter{})
+	_, err = sdk.Conn.LogoutSession(sdk.NewContext())
+	assert.NilError(t, err)
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+	assert.Assert(t, sdk.Conn.SessionExpires().IsZero())
+}
+
+func TestTenantOverride(t *testing.T) {
+	defer gock.OffAll()
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/login").
+		Reply(200).
+		JSON(&dsdk.ApiLogin{Key: "thekey"})
+	for _, tenant := range []string{"/root", "/root/foo", "/root/bar"} {
+		gock.New("http://127.0.0.1:7717").
+			Get("/v1/app_instances/my-ai").
+			MatchHeader("tenant", "^"+tenant+"$").
+			MatchHeader("Auth-Token", "thekey").
+			Reply(200).
+			JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "my-ai", "descr": tenant}})
+	}
+
+	sdk, err := dsdk.NewSDK(&udc.UDC{
+		MgmtIp:     "127.0.0.1",
+		Username:   "foo",
+		Password:   "bar",
+		ApiVersion: "1",
+		Tenant:     "/root",
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ai, err := sdk.AppInstances.GetE(&dsdk.AppInstancesGetRequest{Ctxt: sdk.NewContext(), Id: "my-ai"})
+	assert.NilError(t, err)
+	assert.Equal(t, ai.Descr, "/root")
+	// the scoped view shares the session of the SDK, so no second login
+	ai, err = sdk.ForTenant("/root/foo").AppInstances.GetE(&dsdk.AppInstancesGetRequest{
+		Ctxt: sdk.ForTenant("/root/foo").NewContext(),
+		Id:   "my-ai",
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, ai.Descr, "/root/foo")
+	ai, err = sdk.AppInstances.GetE(&dsdk.AppInstancesGetRequest{
+		Ctxt: dsdk.WithTenant(sdk.NewContext(), "/root/bar"),
+		Id:   "my-ai",
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, ai.Descr, "/root/bar")
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+}
+
+func TestListIterator(t *testing.T) {
+	defer gock.OffAll()
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/login").
+		Reply(200).
+		JSON(&dsdk.ApiLogin{Key: "thekey"})
+	mockPage := func(offset int, names ...string) *gock.Request {
+		data := []interface{}{}
+		for _, n := range names {
+			data = append(data, map[string]interface{}{"name": n})
+		}
+		req := gock.New("http://127.0.0.1:7717").
+			Get("/v1/app_instances").
+			MatchParam("offset", fmt.Sprintf("^%d$", offset)).
+			MatchParam("limit", "^2$")
+		req.Reply(200).
+			JSON(dsdk.ApiListOuter{Data: data, Metadata: map[string]interface{}{"total_count": 5}})
+		return req
+	}
+	mockPage(0, "ai-1", "ai-2")
+	mockPage(2, "ai-3", "ai-4")
+	mockPage(4, "ai-5")
+
+	sdk, err := dsdk.NewSDK(&udc.UDC{
+		MgmtIp:     "127.0.0.1",
+		Username:   "foo",
+		Password:   "bar",
+		ApiVersion: "1",
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	it := sdk.AppInstances.Iter(sdk.NewContext(), dsdk.ListParams{Limit: 2})
+	for it.Next() {
+		names = append(names, it.Value().Name)
+	}
+	assert.NilError(t, it.Err())
+	assert.DeepEqual(t, names, []string{"ai-1", "ai-2", "ai-3", "ai-4", "ai-5"})
+	assert.Equal(t, it.Total(), 5)
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+
+	// stopping early doesn't fetch the remaining pages
+	mockPage(0, "ai-1", "ai-2")
+	mockPage(2, "ai-3", "ai-4")
+	last := mockPage(4, "ai-5")
+	it = sdk.AppInstances.Iter(sdk.NewContext(), dsdk.ListParams{Limit: 2})
+	for i := 0; i < 3 && it.Next(); i++ {
+	}
+	assert.NilError(t, it.Err())
+	assert.Equal(t, it.Value().Name, "ai-3")
+	assert.Assert(t, last.Mock.Done() == false, "the last page shouldn't have been fetched")
+	gock.Flush()
+
+	// a done context stops the iteration with its error
+	ctxt, cancel := context.WithCancel(sdk.NewContext())
+	cancel()
+	it = sdk.AppInstances.Iter(ctxt, dsdk.ListParams{Limit: 2})
+	assert.Assert(t, !it.Next())
+	assert.Assert(t, errors.Is(it.Err(), context.Canceled))
+
+	// so does a failed request
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/app_instances").
+		Reply(400).
+		JSON(dsdk.ApiErrorResponse{Name: "ValidationFailedError", Http: 400, Message: "bad offset"})
+	it = sdk.AppInstances.Iter(sdk.NewContext(), dsdk.ListParams{})
+	assert.Assert(t, !it.Next())
+	var apierr *dsdk.ApiErrorResponse
+	assert.Assert(t, errors.As(it.Err(), &apierr))
+	assert.Equal(t, apierr.Message, "bad offset")
+}
+
+func TestParallelPages(t *testing.T) {
+	defer gock.OffAll()
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/login").
+		Reply(200).
+		JSON(&dsdk.ApiLogin{Key: "thekey"})
+	mockPage := func(offset int, delay time.Duration, names ...string) *gock.Response {
+		data := []interface{}{}
+		for _, n := range names {
+			data = append(data, map[string]interface{}{"name": n})
+		}
+		req := gock.New("http://127.0.0.1:7717").Get("/v1/storage_nodes")
+		if offset > 0 {
+			req.MatchParam("offset", fmt.Sprintf("^%d$", offset)).MatchParam("limit", "^2$")
+		}
+		return req.Reply(200).
+			Delay(delay).
+			JSON(dsdk.ApiListOuter{Data: data, Metadata: map[string]interface{}{"total_count": 7}})
+	}
+	mockPage(2, 50*time.Millisecond, "sn-3", "sn-4")
+	mockPage(4, 0, "sn-5", "sn-6")
+	mockPage(6, 0, "sn-7")
+	// the first page is requested without an offset, so it is mocked last
+	mockPage(0, 0, "sn-1", "sn-2")
+
+	sdk, err := dsdk.NewSDK(&udc.UDC{
+		MgmtIp:     "127.0.0.1",
+		Username:   "foo",
+		Password:   "bar",
+		ApiVersion: "1",
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sdk.Conn.SetPageWorkers(3)
+
+	sns, err := sdk.StorageNodes.ListE(&dsdk.StorageNodesListRequest{Ctxt: sdk.NewContext()})
+	assert.NilError(t, err)
+	names := []string{}
+	for _, sn := range sns {
+		names = append(names, sn.Name)
+	}
+	// the slow second page doesn't change the order
+	assert.DeepEqual(t, names, []string{"sn-1", "sn-2", "sn-3", "sn-4", "sn-5", "sn-6", "sn-7"})
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+
+	// the first failure is returned along with the pages before it, the
+	// pages after it are cancelled
+	mockPage(2, 0, "sn-3", "sn-4")
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/storage_nodes").
+		MatchParam("offset", "^4$").
+		Reply(400).
+		Delay(20 * time.Millisecond).
+		JSON(dsdk.ApiErrorResponse{Name: "ValidationFailedError", Http: 400, Message: "bad offset"})
+	mockPage(6, 200*time.Millisecond, "sn-7")
+	mockPage(0, 0, "sn-1", "sn-2")
+	rs, apierr, err := sdk.Conn.GetList(sdk.NewContext(), "storage_nodes", &greq.RequestOptions{})
+	assert.NilError(t, err)
+	assert.Assert(t, apierr != nil)
+	assert.Equal(t, apierr.Message, "bad offset")
+	assert.Equal(t, rs.TotalCount(), 7)
+	assert.Equal(t, len(rs.Data), 4)
+}
+
+func TestResponseCaching(t *testing.T) {
+	defer gock.OffAll()
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/login").
+		Reply(200).
+		JSON(&dsdk.ApiLogin{Key: "thekey"})
+	mockGet := func(descr string) {
+		gock.New("http://127.0.0.1:7717").
+			Get("/v1/app_instances/my-ai").
+			Reply(200).
+			JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "my-ai", "descr": descr}})
+	}
+	mockGet("first")
+
+	sdk, err := dsdk.NewSDK(&udc.UDC{
+		MgmtIp:     "127.0.0.1",
+		Username:   "foo",
+		Password:   "bar",
+		ApiVersion: "1",
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sdk.Conn.SetCache(dsdk.CacheConfig{TTL: time.Minute})
+	get := func(ctxt context.Context) string {
+		ai, err := sdk.AppInstances.GetE(&dsdk.AppInstancesGetRequest{Ctxt: ctxt, Id: "my-ai"})
+		assert.NilError(t, err)
+		return ai.Descr
+	}
+
+	assert.Equal(t, get(sdk.NewContext()), "first")
+	// served from the cache, there is no mock left for it
+	assert.Equal(t, get(sdk.NewContext()), "first")
+
+	// bypassing the cache refreshes it
+	mockGet("second")
+	assert.Equal(t, get(dsdk.WithNoCache(sdk.NewContext())), "second")
+	assert.Equal(t, get(sdk.NewContext()), "second")
+
+	// mutations invalidate the path
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/app_instances/my-ai").
+		Reply(200).
+		JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "my-ai", "descr": "third"}})
+	mockGet("third")
+	_, _, err = sdk.Conn.Put(sdk.NewContext(), "app_instances/my-ai", &greq.RequestOptions{JSON: map[string]string{"descr": "third"}})
+	assert.NilError(t, err)
+	assert.Equal(t, get(sdk.NewContext()), "third")
+
+	// a read that was in flight during a mutation doesn't cache what it got
+	// before the mutation
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/app_instances/my-ai").
+		Reply(200).
+		Delay(200 * time.Millisecond).
+		JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "my-ai", "descr": "stale"}})
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/app_instances/my-ai").
+		Reply(200).
+		JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "my-ai", "descr": "fourth"}})
+	mockGet("fourth")
+	done := make(chan string)
+	go func() {
+		ai, _ := sdk.AppInstances.GetE(&dsdk.AppInstancesGetRequest{Ctxt: dsdk.WithNoCache(sdk.NewContext()), Id: "my-ai"})
+		done <- ai.Descr
+	}()
+	time.Sleep(50 * time.Millisecond)
+	_, _, err = sdk.Conn.Put(sdk.NewContext(), "app_instances/my-ai", &greq.RequestOptions{JSON: map[string]string{"descr": "fourth"}})
+	assert.NilError(t, err)
+	assert.Equal(t, <-done, "stale")
+	assert.Equal(t, get(sdk.NewContext()), "fourth")
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+}
+
+func TestCoalescedReads(t *testing.T) {
+	defer gock.OffAll()
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/login").
+		Reply(200).
+		JSON(&dsdk.ApiLogin{Key: "thekey"})
+	// a single response for every caller, a second request wouldn't match
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/system").
+		Reply(200).
+		Delay(100 * time.Millisecond).
+		JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "the system", "tags": []interface{}{"a"}}})
+
+	sdk, err := dsdk.NewSDK(&udc.UDC{
+		MgmtIp:     "127.0.0.1",
+		Username:   "foo",
+		Password:   "bar",
+		ApiVersion: "1",
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// log in up front so every read starts at the same time
+	_, err = sdk.Conn.Login(sdk.NewContext())
+	assert.NilError(t, err)
+
+	const n = 20
+	results := make([]*dsdk.ApiOuter, n)
+	errs := make([]error, n)
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rs, apierr, err := sdk.Conn.Get(sdk.NewContext(), "system", nil)
+			results[i], errs[i] = rs, dsdk.AsError(apierr, err)
+			// nobody else sees what a caller does with its result
+			rs.Data["name"] = fmt.Sprintf("caller %d", i)
+			rs.Data["tags"].([]interface{})[0] = i
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < n; i++ {
+		assert.NilError(t, errs[i])
+		assert.Equal(t, results[i].Data["name"], fmt.Sprintf("caller %d", i))
+		assert.Equal(t, results[i].Data["tags"].([]interface{})[0], i)
+	}
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	defer gock.OffAll()
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/login").
+		Reply(200).
+		JSON(&dsdk.ApiLogin{Key: "thekey"})
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/system").
+		Times(2).
+		Reply(503).
+		JSON(dsdk.ApiErrorResponse{Name: "ServiceUnavailableError", Http: 503})
+
+	sdk, err := dsdk.NewSDK(&udc.UDC{
+		MgmtIp:     "127.0.0.1",
+		Username:   "foo",
+		Password:   "bar",
+		ApiVersion: "1",
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan dsdk.CircuitState, 10)
+	sdk.Conn.SetCircuitBreaker(dsdk.CircuitBreakerConfig{
+		Threshold: 2,
+		Cooldown:  50 * time.Millisecond,
+		OnStateChange: func(route string, from, to dsdk.CircuitState) {
+			changes <- to
+		},
+	})
+	get := func() error {
+		_, apierr, err := sdk.Conn.Get(dsdk.WithRetryPolicy(sdk.NewContext(), dsdk.NoRetryPolicy), "system", nil)
+		return dsdk.AsError(apierr, err)
+	}
+
+	assert.Assert(t, errors.Is(get(), dsdk.ErrUnavailable))
+	assert.Assert(t, errors.Is(get(), dsdk.ErrUnavailable))
+	assert.Equal(t, <-changes, dsdk.CircuitOpen)
+	// rejected without a request, none is mocked
+	err = get()
+	assert.Assert(t, errors.Is(err, dsdk.ErrCircuitOpen), err)
+	var coerr *dsdk.CircuitOpenError
+	assert.Assert(t, errors.As(err, &coerr))
+
+	time.Sleep(50 * time.Millisecond)
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/system").
+		Reply(200).
+		JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "the system"}})
+	assert.NilError(t, get())
+	assert.Equal(t, <-changes, dsdk.CircuitHalfOpen)
+	assert.Equal(t, <-changes, dsdk.CircuitClosed)
+	assert.Equal(t, sdk.Conn.CircuitState(""), dsdk.CircuitClosed)
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+}
+
+func TestCreateOrAdopt(t *testing.T) {
+	defer gock.OffAll()
+	gock.New("http://127.0.0.1:7717").
+		Put("/v1/login").
+		Reply(200).
+		JSON(&dsdk.ApiLogin{Key: "thekey"})
+	mockUnavailable := func() {
+		gock.New("http://127.0.0.1:7717").
+			Post("/v1/app_instances").
+			Reply(503).
+			JSON(dsdk.ApiErrorResponse{Name: "ServiceUnavailableError", Http: 503})
+	}
+	mockExisting := func(descr string) {
+		gock.New("http://127.0.0.1:7717").
+			Get("/v1/app_instances/my-ai").
+			Reply(200).
+			JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "my-ai", "descr": descr, "id": "the-id"}})
+	}
+
+	sdk, err := dsdk.NewSDK(&udc.UDC{
+		MgmtIp:     "127.0.0.1",
+		Username:   "foo",
+		Password:   "bar",
+		ApiVersion: "1",
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := func() *dsdk.AppInstancesCreateRequest {
+		return &dsdk.AppInstancesCreateRequest{Ctxt: sdk.NewContext(), Name: "my-ai", Descr: "mine"}
+	}
+
+	// the 503 came after the AppInstance was created
+	mockUnavailable()
+	mockExisting("mine")
+	ai, outcome, err := sdk.AppInstances.CreateOrAdoptE(req())
+	assert.NilError(t, err)
+	assert.Equal(t, outcome, dsdk.Adopted)
+	assert.Equal(t, ai.Id, "the-id")
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+
+	// something else with the same name is in the way
+	mockUnavailable()
+	mockExisting("not mine")
+	_, _, err = sdk.AppInstances.CreateOrAdoptE(req())
+	assert.Assert(t, errors.Is(err, dsdk.ErrConflict), err)
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+
+	// the AppInstance wasn't created, so the retry creates it
+	mockUnavailable()
+	gock.New("http://127.0.0.1:7717").
+		Get("/v1/app_instances/my-ai").
+		Reply(404).
+		JSON(dsdk.ApiErrorResponse{Name: "NotFoundError", Http: 404})
+	gock.New("http://127.0.0.1:7717").
+		Post("/v1/app_instances").
+		Reply(200).
+		JSON(dsdk.ApiOuter{Data: map[string]interface{}{"name": "my-ai", "descr": "mine", "id": "new-id"}})
+	ai, outcome, err = sdk.AppInstances.CreateOrAdoptE(req())
+	assert.NilError(t, err)
+	assert.Equal(t, outcome, dsdk.Created)
+	assert.Equal(t, ai.Id, "new-id")
+	assert.Assert(t, gock.IsDone(), "not all mocked requests were made")
+
+	// the AppInstance existed before the call, even a policy retrying 409s
+	// mustn't adopt it
+	for i := 0; i < 2; i++ {
+		gock.New("http://127.0.0.1:7717").
+			Post("/v1/app_instances").
+			Reply(409).
+			JSON(dsdk.ApiErrorResponse{Name: "ConflictError", Http: 409})
+	}
+	mockExisting("mine")
+	r := req()
+	r.Ctxt = dsdk.WithRetryPolicy(r.Ctxt, &dsdk.ExponentialBackoffPolicy{
+		Statuses:        []int{409, 503},
+		InitialInterval: 10 * time.Millisecond,
+		Multiplier:      10,
+		MaxElapsed:      50 * time.Millisecond,
+	})
+	_, outcome, err = sdk.AppInstances.CreateOrAdoptE(r)
+	assert.Assert(t, errors.Is(err, dsdk.ErrConflict), err)
+	assert.Equal(t, outcome, dsdk.Created)
+	assert.Equal(t, len(gock.Pending()), 1, "the existing AppInstance was looked up")
+}
+
+func TestNewApiConnectionBadEndpoint(t *testing.T) {
+	conf := &udc.UDC{MgmtIp: "bad%zzhost", ApiVersion: "2.2"}
+	if _, err := dsdk.NewApiConnectionWithHTTPClientE(conf, false, nil); err == nil {
+		t.Error("expected an error for an invalid management IP")
+	}
+	if conn := dsdk.NewApiConnection(conf, false); conn != nil {
+		t.Error("expected no connection for an invalid management IP")
+	}
+	if _, err := dsdk.NewSDKWithHTTPClient(conf, false, nil); err == nil {
+		t.Error("expected NewSDKWithHTTPClient to return the error")
+	}
+}
+
 func TestConcurrentUsage(t *testing.T) {
 	originalTO := dsdk.RetryTimeout
 	dsdk.RetryTimeout = int64(5) // lower the retry timeout so any test failures that result in a retry loop don't take 5 minutes